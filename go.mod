@@ -1,8 +1,11 @@
 module snowy_viewer
 
-go 1.21
+go 1.22
 
-require github.com/redis/go-redis/v9 v9.7.0
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/redis/go-redis/v9 v9.7.0
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
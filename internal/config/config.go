@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,22 +13,76 @@ type Config struct {
 	BilibiliCookie   string
 	Port             string
 	MasterDataPath   string
+	// Regions lists the regions to load, by default only DefaultRegion.
+	// Others are opt-in since regions without local data are fetched remotely.
+	Regions       []string
+	DefaultRegion string
+	// UpdateInterval is how often remote master data is checked; 0, the
+	// default, disables it
+	UpdateInterval time.Duration
+	// WatchInterval is how often local master files are polled; 0 disables it
+	WatchInterval time.Duration
+	WatchDebounce time.Duration
 }
 
 func Load() *Config {
+	defaultRegion := strings.ToLower(getEnv("DEFAULT_REGION", "jp"))
 	cfg := &Config{
 		RedisURL:         getEnv("REDIS_URL", "localhost:6379"),
 		BilibiliSessData: os.Getenv("BILIBILI_SESSDATA"),
 		BilibiliCookie:   os.Getenv("BILIBILI_COOKIE"),
 		Port:             getEnv("PORT", "8080"),
 		MasterDataPath:   getEnv("MASTER_DATA_PATH", "./data/master"),
+		Regions:          splitList(getEnv("REGIONS", defaultRegion)),
+		DefaultRegion:    defaultRegion,
+		UpdateInterval:   getDuration("MASTER_UPDATE_INTERVAL", 0),
+		WatchInterval:    getDuration("MASTER_WATCH_INTERVAL", 0),
+		WatchDebounce:    getDuration("MASTER_WATCH_DEBOUNCE", 2*time.Second),
 	}
 	return cfg
 }
 
+// MasterDataPathFor returns the local master data directory of a region.
+// The default region uses MASTER_DATA_PATH, others MASTER_DATA_PATH_<REGION>.
+func (c *Config) MasterDataPathFor(region string) string {
+	if region == c.DefaultRegion {
+		return c.MasterDataPath
+	}
+	return getEnv("MASTER_DATA_PATH_"+strings.ToUpper(region), "./data/master_"+region)
+}
+
+// MasterBaseURLsFor returns the remote base URLs configured for a region
+// via MASTER_BASE_URLS_<REGION>, or nil to use the built-in defaults.
+func (c *Config) MasterBaseURLsFor(region string) []string {
+	return splitList(os.Getenv("MASTER_BASE_URLS_" + strings.ToUpper(region)))
+}
+
 func getEnv(key, defaultValue string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return defaultValue
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fmt.Printf("Warning: invalid %s %q, using %s\n", key, v, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,617 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Error codes reported in error extensions
+const (
+	CodeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeTooDeep          = "QUERY_TOO_DEEP"
+	CodeTooComplex       = "QUERY_TOO_COMPLEX"
+	CodeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response. Data is nil when the request failed before
+// execution started, e.g. on syntax, validation or limit errors.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error
+type Error struct {
+	Message    string            `json:"message"`
+	Path       []any             `json:"path,omitempty"`
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Extensions: map[string]string{"code": code}}
+}
+
+// Result is an object value whose keys keep the order of the query
+type Result struct {
+	keys   []string
+	values []any
+}
+
+// MarshalJSON writes the keys in query order
+func (r *Result) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses, validates and runs a query against root. Execution errors
+// null the failing field and are reported next to the data.
+func (s *Schema) Execute(ctx context.Context, req Request, root any) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{newError(CodeParseFailed, "%s", err)}}
+	}
+	op, opErr := selectOperation(doc, req.OperationName)
+	if opErr != nil {
+		return &Response{Errors: []*Error{opErr}}
+	}
+	vars, errs := variableValues(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	v := &validator{
+		schema:        s,
+		doc:           doc,
+		op:            op,
+		vars:          vars,
+		spreading:     make(map[string]bool),
+		fragmentCosts: make(map[fragmentAt]int),
+	}
+	v.selections(s.Query, op.Selections, 1, nil)
+	if len(v.errors) == 0 {
+		// Only run on valid queries, whose fragments are known and acyclic
+		v.mergeable(s.Query, op.Selections, nil)
+	}
+	if len(v.errors) > 0 {
+		return &Response{Errors: v.errors}
+	}
+
+	e := &executor{ctx: ctx, doc: doc, vars: vars}
+	data := e.object(s.Query, root, op.Selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, *Error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, newError(CodeValidationFailed, "operationName is required when the document has several operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, newError(CodeValidationFailed, "unknown operation %q", name)
+}
+
+// variableValues merges the given variables with declared defaults
+func variableValues(op *Operation, given map[string]any) (map[string]any, []*Error) {
+	vars := make(map[string]any)
+	var errs []*Error
+	for _, def := range op.Variables {
+		if v, ok := given[def.Name]; ok && v != nil {
+			vars[def.Name] = v
+			continue
+		}
+		if def.HasValue {
+			if v, _, err := inputValue(def.Default, nil); err == nil && v != nil {
+				vars[def.Name] = v
+			}
+			continue
+		}
+		if def.NonNull {
+			errs = append(errs, newError(CodeBadUserInput, "variable $%s of required type %s! was not provided", def.Name, def.Type))
+		}
+	}
+	for name := range given {
+		if !declaresVariable(op, name) {
+			errs = append(errs, newError(CodeBadUserInput, "variable $%s is not declared by the operation", name))
+		}
+	}
+	return vars, errs
+}
+
+func declaresVariable(op *Operation, name string) bool {
+	for _, def := range op.Variables {
+		if def.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// undeclaredVariable finds a variable referenced in a value that the
+// operation does not declare
+func undeclaredVariable(op *Operation, v Value) (string, bool) {
+	switch v := v.(type) {
+	case Variable:
+		return string(v), !declaresVariable(op, string(v))
+	case []Value:
+		for _, item := range v {
+			if name, ok := undeclaredVariable(op, item); ok {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// errUndefinedVariable marks a reference to a variable the operation does not declare
+var errUndefinedVariable = errors.New("undefined variable")
+
+// inputValue resolves variables in a literal, returning plain JSON-like values.
+// It reports false when the value is a variable that was not provided.
+func inputValue(v Value, vars map[string]any) (any, bool, error) {
+	switch v := v.(type) {
+	case Variable:
+		if vars == nil {
+			return nil, false, errUndefinedVariable
+		}
+		value, ok := vars[string(v)]
+		return value, ok, nil
+	case Enum:
+		return nil, false, fmt.Errorf("enum value %s is not supported", string(v))
+	case []Value:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			value, ok, err := inputValue(item, vars)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				list = append(list, value)
+			}
+		}
+		return list, true, nil
+	case map[string]Value:
+		return nil, false, fmt.Errorf("input objects are not supported")
+	case nil:
+		return nil, false, nil
+	}
+	return v, true, nil
+}
+
+// coerceInput converts a JSON-like value to the Go type of an argument
+func coerceInput(v any, t ArgType) (any, error) {
+	switch t {
+	case Int:
+		switch n := v.(type) {
+		case int64:
+			if n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int(n), nil
+			}
+		case float64:
+			if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+				return int(n), nil
+			}
+		}
+	case Float:
+		switch n := v.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+	case String:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case IntList, StringList:
+		items, ok := v.([]any)
+		if !ok {
+			// A single value is accepted where a list is expected
+			items = []any{v}
+		}
+		elem := Int
+		if t == StringList {
+			elem = String
+		}
+		var ints []int
+		var strs []string
+		for _, item := range items {
+			c, err := coerceInput(item, elem)
+			if err != nil {
+				return nil, err
+			}
+			if t == IntList {
+				ints = append(ints, c.(int))
+			} else {
+				strs = append(strs, c.(string))
+			}
+		}
+		if t == IntList {
+			return ints, nil
+		}
+		return strs, nil
+	}
+	return nil, fmt.Errorf("%s cannot represent %s", t, describeValue(v))
+}
+
+func describeValue(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// coerceArgs coerces the arguments of a field selection
+func coerceArgs(field *Field, sel Selection, vars map[string]any) (Args, error) {
+	args := make(Args)
+	for _, arg := range sel.Arguments {
+		t, ok := field.Args[arg.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument %q on field %q", arg.Name, sel.Name)
+		}
+		value, given, err := inputValue(arg.Value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.Name, err)
+		}
+		if !given || value == nil {
+			continue
+		}
+		if args[arg.Name], err = coerceInput(value, t); err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.Name, err)
+		}
+	}
+	return args, nil
+}
+
+// validator checks fields, arguments and fragments against the schema and
+// computes the query complexity. Each field costs 1; the cost of the
+// selections below a list field is multiplied by its limit argument, or by
+// the schema's default list size. Validation stops as soon as the cost
+// passes the schema's MaxComplexity.
+type validator struct {
+	schema    *Schema
+	doc       *Document
+	op        *Operation
+	vars      map[string]any
+	spreading map[string]bool
+	// fragmentCosts caches the cost of each fragment spread at a given depth
+	fragmentCosts map[fragmentAt]int
+	tooDeep       bool
+	tooComplex    bool
+	errors        []*Error
+}
+
+// fragmentAt is a fragment spread at a selection depth
+type fragmentAt struct {
+	name  string
+	depth int
+}
+
+func (v *validator) fail(code string, path []any, format string, args ...any) {
+	err := newError(code, format, args...)
+	err.Path = path
+	v.errors = append(v.errors, err)
+}
+
+// exceeds reports whether cost is above the complexity limit, failing the
+// query the first time it is
+func (v *validator) exceeds(cost int, path []any) bool {
+	limit := v.schema.MaxComplexity
+	if limit <= 0 || cost <= limit {
+		return false
+	}
+	if !v.tooComplex {
+		v.tooComplex = true
+		v.fail(CodeTooComplex, path, "query complexity exceeds the limit of %d", limit)
+	}
+	return true
+}
+
+func (v *validator) selections(obj *Object, sels []Selection, depth int, path []any) int {
+	cost := 0
+	for _, sel := range sels {
+		if v.tooComplex {
+			return cost
+		}
+		switch {
+		case sel.FragmentSpread != "":
+			frag, ok := v.doc.Fragments[sel.FragmentSpread]
+			if !ok {
+				v.fail(CodeValidationFailed, path, "unknown fragment %q", sel.FragmentSpread)
+				continue
+			}
+			if v.spreading[frag.Name] {
+				v.fail(CodeValidationFailed, path, "fragment %q spreads itself", frag.Name)
+				continue
+			}
+			if frag.TypeCondition != obj.Name {
+				v.fail(CodeValidationFailed, path, "fragment %q on %s cannot be spread on %s", frag.Name, frag.TypeCondition, obj.Name)
+				continue
+			}
+			key := fragmentAt{frag.Name, depth}
+			fragCost, ok := v.fragmentCosts[key]
+			if !ok {
+				v.spreading[frag.Name] = true
+				fragCost = v.selections(obj, frag.Selections, depth, path)
+				delete(v.spreading, frag.Name)
+				v.fragmentCosts[key] = fragCost
+			}
+			cost += fragCost
+		case sel.Inline:
+			if sel.TypeCondition != "" && sel.TypeCondition != obj.Name {
+				v.fail(CodeValidationFailed, path, "inline fragment on %s cannot be spread on %s", sel.TypeCondition, obj.Name)
+				continue
+			}
+			cost += v.selections(obj, sel.Selections, depth, path)
+		default:
+			cost += v.field(obj, sel, depth, append(path[:len(path):len(path)], sel.ResponseKey()))
+		}
+		if v.exceeds(cost, path) {
+			return cost
+		}
+	}
+	return cost
+}
+
+func (v *validator) field(obj *Object, sel Selection, depth int, path []any) int {
+	if v.schema.MaxDepth > 0 && depth > v.schema.MaxDepth {
+		if !v.tooDeep {
+			v.tooDeep = true
+			v.fail(CodeTooDeep, path, "query depth exceeds the limit of %d", v.schema.MaxDepth)
+		}
+		return 0
+	}
+	if sel.Name == "__typename" {
+		if len(sel.Arguments) > 0 || len(sel.Selections) > 0 {
+			v.fail(CodeValidationFailed, path, "__typename takes no arguments or selections")
+		}
+		return 1
+	}
+	field, ok := obj.Fields[sel.Name]
+	if !ok {
+		v.fail(CodeValidationFailed, path, "cannot query field %q on type %s", sel.Name, obj.Name)
+		return 0
+	}
+	for _, arg := range sel.Arguments {
+		if name, ok := undeclaredVariable(v.op, arg.Value); ok {
+			v.fail(CodeValidationFailed, path, "variable $%s is not defined by the operation", name)
+			return 0
+		}
+	}
+	args, err := coerceArgs(field, sel, v.vars)
+	if err != nil {
+		v.fail(CodeBadUserInput, path, "%s", err)
+		return 0
+	}
+	if field.Type == nil {
+		if len(sel.Selections) > 0 {
+			v.fail(CodeValidationFailed, path, "field %q of type %s is a leaf and takes no selections", sel.Name, obj.Name)
+		}
+		return 1
+	}
+	if len(sel.Selections) == 0 {
+		v.fail(CodeValidationFailed, path, "field %q of type %s must have a selection of subfields", sel.Name, field.Type.Name)
+		return 0
+	}
+	childCost := v.selections(field.Type, sel.Selections, depth+1, path)
+	if !field.List || v.tooComplex {
+		return 1 + childCost
+	}
+	size := args.Int("limit", v.schema.DefaultListSize)
+	if size < 1 {
+		size = v.schema.DefaultListSize
+	}
+	// Checked before multiplying so that large limits cannot overflow
+	if limit := v.schema.MaxComplexity; limit > 0 && childCost > 0 && size > limit/childCost {
+		v.exceeds(limit+1, path)
+		return limit + 1
+	}
+	return 1 + size*childCost
+}
+
+// mergeable checks that the fields sharing a response key, which execution
+// merges into one, select the same field with the same arguments, and does so
+// again within their merged selections
+func (v *validator) mergeable(obj *Object, sels []Selection, path []any) {
+	for _, f := range collectFields(v.doc, sels, nil) {
+		fieldPath := append(path[:len(path):len(path)], f.key)
+		conflict := false
+		for _, other := range f.merged[1:] {
+			switch {
+			case other.Name != f.sel.Name:
+				v.fail(CodeValidationFailed, fieldPath, "fields %q and %q conflict on response key %q; use different aliases", f.sel.Name, other.Name, f.key)
+			case !sameArguments(f.sel.Arguments, other.Arguments):
+				v.fail(CodeValidationFailed, fieldPath, "field %q is selected with different arguments on response key %q; use different aliases", f.sel.Name, f.key)
+			default:
+				continue
+			}
+			conflict = true
+			break
+		}
+		if conflict {
+			continue
+		}
+		if field, ok := obj.Fields[f.sel.Name]; ok && field.Type != nil {
+			v.mergeable(field.Type, f.selections, fieldPath)
+		}
+	}
+}
+
+// sameArguments reports whether two argument lists are equal regardless of order
+func sameArguments(a, b []Argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	values := make(map[string]Value, len(a))
+	for _, arg := range a {
+		values[arg.Name] = arg.Value
+	}
+	for _, arg := range b {
+		value, ok := values[arg.Name]
+		if !ok || !reflect.DeepEqual(value, arg.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// executor resolves a validated operation
+type executor struct {
+	ctx    context.Context
+	doc    *Document
+	vars   map[string]any
+	errors []*Error
+}
+
+// collectedField is a response key with the selections merged into it
+type collectedField struct {
+	key string
+	sel Selection
+	// merged holds every field selected under the key, sel first
+	merged     []Selection
+	selections []Selection
+}
+
+// collectFields flattens fragments and merges selections sharing a response key
+func collectFields(doc *Document, sels []Selection, fields []collectedField) []collectedField {
+	for _, sel := range sels {
+		switch {
+		case sel.FragmentSpread != "":
+			if frag, ok := doc.Fragments[sel.FragmentSpread]; ok {
+				fields = collectFields(doc, frag.Selections, fields)
+			}
+		case sel.Inline:
+			fields = collectFields(doc, sel.Selections, fields)
+		default:
+			key := sel.ResponseKey()
+			merged := false
+			for i := range fields {
+				if fields[i].key == key {
+					fields[i].merged = append(fields[i].merged, sel)
+					fields[i].selections = append(fields[i].selections, sel.Selections...)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				fields = append(fields, collectedField{key: key, sel: sel, merged: []Selection{sel}, selections: sel.Selections})
+			}
+		}
+	}
+	return fields
+}
+
+func (e *executor) object(obj *Object, source any, sels []Selection, path []any) *Result {
+	result := &Result{}
+	for _, f := range collectFields(e.doc, sels, nil) {
+		fieldPath := append(path[:len(path):len(path)], f.key)
+		result.keys = append(result.keys, f.key)
+		result.values = append(result.values, e.field(obj, source, f, fieldPath))
+	}
+	return result
+}
+
+func (e *executor) field(obj *Object, source any, f collectedField, path []any) (value any) {
+	if f.sel.Name == "__typename" {
+		return obj.Name
+	}
+	field, ok := obj.Fields[f.sel.Name]
+	if !ok {
+		e.fieldError(path, CodeValidationFailed, fmt.Sprintf("cannot query field %q on type %s", f.sel.Name, obj.Name))
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.fieldError(path, CodeInternal, fmt.Sprintf("internal error resolving %s.%s", obj.Name, f.sel.Name))
+			fmt.Printf("graphql: panic resolving %s.%s: %v\n", obj.Name, f.sel.Name, r)
+			value = nil
+		}
+	}()
+	args, _ := coerceArgs(field, f.sel, e.vars)
+	resolved, err := field.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+	if err != nil {
+		e.fieldError(path, CodeInternal, err.Error())
+		return nil
+	}
+	if field.Type == nil {
+		return resolved
+	}
+
+	rv := reflect.ValueOf(resolved)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Kind() == reflect.Interface {
+			rv = rv.Elem()
+			continue
+		}
+		break
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if !field.List {
+		return e.object(field.Type, resolved, f.selections, path)
+	}
+	if rv.Kind() != reflect.Slice {
+		e.fieldError(path, CodeInternal, fmt.Sprintf("%s.%s did not resolve to a list", obj.Name, f.sel.Name))
+		return nil
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = e.object(field.Type, rv.Index(i).Interface(), f.selections, append(path[:len(path):len(path)], i))
+	}
+	return items
+}
+
+func (e *executor) fieldError(path []any, code, message string) {
+	e.errors = append(e.errors, &Error{Message: message, Path: path, Extensions: map[string]string{"code": code}})
+}
@@ -0,0 +1,510 @@
+// Package graphql implements the subset of GraphQL needed to query master
+// data: queries with variables, aliases, arguments and fragments. Mutations,
+// subscriptions, directives and introspection are not supported.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed GraphQL document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query definition
+type Operation struct {
+	Name       string
+	Variables  []VariableDefinition
+	Selections []Selection
+}
+
+// VariableDefinition declares an operation variable, e.g. $id: Int! = 1
+type VariableDefinition struct {
+	Name     string
+	Type     string
+	NonNull  bool
+	Default  Value
+	HasValue bool
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+// Selection is a field, a fragment spread (FragmentSpread set) or an inline
+// fragment (Inline set)
+type Selection struct {
+	Alias      string
+	Name       string
+	Arguments  []Argument
+	Selections []Selection
+
+	FragmentSpread string
+	Inline         bool
+	TypeCondition  string
+
+	Line int
+}
+
+// ResponseKey is the alias of a field, or its name
+func (s Selection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Argument is a field argument
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Value is a literal: int64, float64, string, bool, nil, Enum, Variable,
+// []Value or map[string]Value
+type Value any
+
+// Enum is an enum literal
+type Enum string
+
+// Variable references an operation variable
+type Variable string
+
+// SyntaxError reports a malformed document
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error on line %d: %s", e.Line, e.Message)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+}
+
+// lex splits a document into tokens, dropping whitespace, commas and comments
+func lex(src string) ([]token, error) {
+	src = strings.TrimPrefix(src, "\uFEFF")
+	var tokens []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokenPunct, "...", line})
+			i += 3
+		case strings.ContainsRune("!$():=@[]{}|&", rune(c)):
+			tokens = append(tokens, token{tokenPunct, string(c), line})
+			i++
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= '0' && src[i] <= '9' ||
+				src[i] >= 'A' && src[i] <= 'Z' || src[i] >= 'a' && src[i] <= 'z') {
+				i++
+			}
+			tokens = append(tokens, token{tokenName, src[start:i], line})
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			kind := tokenInt
+			i++
+			for i < len(src) {
+				d := src[i]
+				if d >= '0' && d <= '9' {
+					i++
+				} else if d == '.' || d == 'e' || d == 'E' || (d == '+' || d == '-') && (src[i-1] == 'e' || src[i-1] == 'E') {
+					kind = tokenFloat
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, token{kind, src[start:i], line})
+		case c == '"':
+			value, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &SyntaxError{Line: line, Message: err.Error()}
+			}
+			line += strings.Count(src[i:i+n], "\n")
+			tokens = append(tokens, token{tokenString, value, line})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, &SyntaxError{Line: line, Message: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, line: line}), nil
+}
+
+// lexString reads a string or block string literal at the start of src,
+// returning its value and length in src
+func lexString(src string) (string, int, error) {
+	if strings.HasPrefix(src, `"""`) {
+		end := strings.Index(src[3:], `"""`)
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated block string")
+		}
+		return strings.TrimSpace(src[3 : 3+end]), end + 6, nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch e := src[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, fmt.Errorf("bad unicode escape")
+				}
+				code, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("bad unicode escape")
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// maxNesting bounds how deeply selection sets and list or object values may
+// nest, so that parsing cannot recurse without limit
+const maxNesting = 32
+
+type parser struct {
+	tokens []token
+	pos    int
+	// depth is the current nesting of selection sets and values
+	depth int
+}
+
+func (p *parser) enter() {
+	p.depth++
+	if p.depth > maxNesting {
+		p.fail("nesting exceeds the limit of %d", maxNesting)
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// Parse parses a GraphQL document
+func Parse(src string) (doc *Document, err error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+	return p.document(), nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) fail(format string, args ...any) {
+	panic(&SyntaxError{Line: p.peek().line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+func (p *parser) skipPunct(value string) bool {
+	if p.isPunct(value) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectPunct(value string) {
+	if !p.skipPunct(value) {
+		p.fail("expected %q, found %q", value, p.peek().value)
+	}
+}
+
+func (p *parser) expectName() string {
+	t := p.next()
+	if t.kind != tokenName {
+		p.fail("expected name, found %q", t.value)
+	}
+	return t.value
+}
+
+func (p *parser) document() *Document {
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.peek().kind != tokenEOF {
+		switch t := p.peek(); {
+		case t.kind == tokenPunct && t.value == "{":
+			doc.Operations = append(doc.Operations, &Operation{Selections: p.selectionSet()})
+		case t.kind == tokenName && t.value == "query":
+			p.next()
+			doc.Operations = append(doc.Operations, p.operation())
+		case t.kind == tokenName && (t.value == "mutation" || t.value == "subscription"):
+			p.fail("%s operations are not supported", t.value)
+		case t.kind == tokenName && t.value == "fragment":
+			p.next()
+			f := p.fragment()
+			if _, dup := doc.Fragments[f.Name]; dup {
+				p.fail("fragment %q defined twice", f.Name)
+			}
+			doc.Fragments[f.Name] = f
+		default:
+			p.fail("unexpected %q", t.value)
+		}
+	}
+	if len(doc.Operations) == 0 {
+		p.fail("document has no operation")
+	}
+	return doc
+}
+
+func (p *parser) operation() *Operation {
+	op := &Operation{}
+	if p.peek().kind == tokenName {
+		op.Name = p.next().value
+	}
+	if p.skipPunct("(") {
+		for !p.skipPunct(")") {
+			op.Variables = append(op.Variables, p.variableDefinition())
+		}
+	}
+	p.directives()
+	op.Selections = p.selectionSet()
+	return op
+}
+
+func (p *parser) variableDefinition() VariableDefinition {
+	p.expectPunct("$")
+	def := VariableDefinition{Name: p.expectName()}
+	p.expectPunct(":")
+	def.Type, def.NonNull = p.typeRef()
+	if p.skipPunct("=") {
+		def.Default = p.value(true)
+		def.HasValue = true
+	}
+	return def
+}
+
+// typeRef reads a type such as Int!, [Int] or [String!]!
+func (p *parser) typeRef() (string, bool) {
+	var typ string
+	if p.skipPunct("[") {
+		inner, innerNonNull := p.typeRef()
+		p.expectPunct("]")
+		if innerNonNull {
+			inner += "!"
+		}
+		typ = "[" + inner + "]"
+	} else {
+		typ = p.expectName()
+	}
+	return typ, p.skipPunct("!")
+}
+
+func (p *parser) fragment() *Fragment {
+	f := &Fragment{Name: p.expectName()}
+	if f.Name == "on" {
+		p.fail("fragment cannot be named \"on\"")
+	}
+	if p.expectName() != "on" {
+		p.fail("expected \"on\" in fragment %q", f.Name)
+	}
+	f.TypeCondition = p.expectName()
+	p.directives()
+	f.Selections = p.selectionSet()
+	return f
+}
+
+// directives rejects directives, which are not supported
+func (p *parser) directives() {
+	if p.isPunct("@") {
+		p.fail("directives are not supported")
+	}
+}
+
+func (p *parser) selectionSet() []Selection {
+	p.expectPunct("{")
+	p.enter()
+	defer p.leave()
+	var selections []Selection
+	for !p.skipPunct("}") {
+		if p.peek().kind == tokenEOF {
+			p.fail("unterminated selection set")
+		}
+		selections = append(selections, p.selection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) selection() Selection {
+	line := p.peek().line
+	if p.skipPunct("...") {
+		if p.peek().kind == tokenName && p.peek().value != "on" {
+			sel := Selection{FragmentSpread: p.next().value, Line: line}
+			p.directives()
+			return sel
+		}
+		sel := Selection{Inline: true, Line: line}
+		if p.peek().kind == tokenName && p.peek().value == "on" {
+			p.next()
+			sel.TypeCondition = p.expectName()
+		}
+		p.directives()
+		sel.Selections = p.selectionSet()
+		return sel
+	}
+
+	sel := Selection{Name: p.expectName(), Line: line}
+	if p.skipPunct(":") {
+		sel.Alias, sel.Name = sel.Name, p.expectName()
+	}
+	if p.skipPunct("(") {
+		for !p.skipPunct(")") {
+			name := p.expectName()
+			p.expectPunct(":")
+			sel.Arguments = append(sel.Arguments, Argument{Name: name, Value: p.value(false)})
+		}
+	}
+	p.directives()
+	if p.isPunct("{") {
+		sel.Selections = p.selectionSet()
+	}
+	return sel
+}
+
+// value reads a literal; constant values may not reference variables
+func (p *parser) value(constant bool) Value {
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			p.fail("invalid integer %q", t.value)
+		}
+		return n
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			p.fail("invalid float %q", t.value)
+		}
+		return f
+	case tokenString:
+		return t.value
+	case tokenName:
+		switch t.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return Enum(t.value)
+	case tokenPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				p.fail("variables are not allowed here")
+			}
+			return Variable(p.expectName())
+		case "[":
+			p.enter()
+			defer p.leave()
+			list := []Value{}
+			for !p.skipPunct("]") {
+				list = append(list, p.value(constant))
+			}
+			return list
+		case "{":
+			p.enter()
+			defer p.leave()
+			obj := map[string]Value{}
+			for !p.skipPunct("}") {
+				name := p.expectName()
+				p.expectPunct(":")
+				obj[name] = p.value(constant)
+			}
+			return obj
+		}
+	}
+	p.pos--
+	p.fail("unexpected %q", t.value)
+	return nil
+}
@@ -0,0 +1,177 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// Object is an object type. Fields are looked up by name; the order in which
+// they are written follows the query.
+type Object struct {
+	Name        string
+	Description string
+	Fields      map[string]*Field
+}
+
+// Field is a field of an object type. A field with a nil Type is a leaf whose
+// resolved value is written as JSON as-is.
+type Field struct {
+	// Type is the object type of the resolved value, nil for leaves
+	Type *Object
+	// List fields resolve to a slice of Type
+	List bool
+	// Args declares the accepted arguments and their types
+	Args        map[string]ArgType
+	Description string
+	Resolve     ResolveFunc
+}
+
+// ResolveFunc resolves a field value from its parent value
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// ResolveParams is passed to resolvers
+type ResolveParams struct {
+	Context context.Context
+	// Source is the parent value; the request Root for query fields
+	Source any
+	// Args holds the coerced arguments that were given
+	Args Args
+}
+
+// ArgType is the type of a field argument
+type ArgType int
+
+const (
+	Int ArgType = iota
+	Float
+	String
+	Boolean
+	IntList
+	StringList
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case Int:
+		return "Int"
+	case Float:
+		return "Float"
+	case String:
+		return "String"
+	case Boolean:
+		return "Boolean"
+	case IntList:
+		return "[Int]"
+	case StringList:
+		return "[String]"
+	}
+	return "Unknown"
+}
+
+// Args holds coerced argument values: int, float64, string, bool, []int or []string
+type Args map[string]any
+
+// Int returns an Int argument, or def when it was not given
+func (a Args) Int(name string, def int) int {
+	if v, ok := a[name].(int); ok {
+		return v
+	}
+	return def
+}
+
+// String returns a String argument, or "" when it was not given
+func (a Args) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+// Bool returns a Boolean argument and whether it was given
+func (a Args) Bool(name string) (bool, bool) {
+	v, ok := a[name].(bool)
+	return v, ok
+}
+
+// Ints returns an [Int] argument
+func (a Args) Ints(name string) []int {
+	v, _ := a[name].([]int)
+	return v
+}
+
+// Strings returns a [String] argument
+func (a Args) Strings(name string) []string {
+	v, _ := a[name].([]string)
+	return v
+}
+
+// Has reports whether an argument was given
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// Schema is an executable schema with its query limits
+type Schema struct {
+	Query *Object
+	// MaxDepth limits how deeply fields may be nested
+	MaxDepth int
+	// MaxComplexity limits the estimated number of resolved fields
+	MaxComplexity int
+	// DefaultListSize is the assumed length of list fields without a limit argument
+	DefaultListSize int
+}
+
+// ScalarFields derives leaf fields from the JSON-tagged fields of a struct,
+// including embedded structs. Only scalars and slices of scalars are included.
+func ScalarFields(model any) map[string]*Field {
+	fields := make(map[string]*Field)
+	addScalarFields(fields, reflect.TypeOf(model), nil)
+	return fields
+}
+
+func addScalarFields(fields map[string]*Field, t reflect.Type, index []int) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		path := append(append([]int(nil), index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			addScalarFields(fields, sf.Type, path)
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !isScalar(sf.Type) {
+			continue
+		}
+		fields[name] = &Field{Resolve: fieldByIndex(path)}
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return isScalar(t.Elem())
+	}
+	return false
+}
+
+func fieldByIndex(index []int) ResolveFunc {
+	return func(p ResolveParams) (any, error) {
+		v := reflect.ValueOf(p.Source)
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		return v.FieldByIndex(index).Interface(), nil
+	}
+}
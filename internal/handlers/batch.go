@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"snowy_viewer/internal/masterdata"
+)
+
+// maxBatchIDs bounds the number of IDs one batch request may look up
+const maxBatchIDs = 500
+
+// BatchRequest maps resource kinds to the IDs to look up, e.g.
+// {"cardCostumes": [1, 2, 3], "gacha": [10]}
+type BatchRequest map[string][]int
+
+// BatchItem is the result of one lookup: its data or an error
+type BatchItem struct {
+	Data  any       `json:"data,omitempty"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+// BatchResponse holds results keyed by kind, then by ID. All items come from
+// the same master data snapshot.
+type BatchResponse struct {
+	Region  string                       `json:"region"`
+	Version string                       `json:"version"`
+	Results map[string]map[int]BatchItem `json:"results"`
+}
+
+// batchResolver looks up one entity of a kind in a snapshot
+type batchResolver func(snap *masterdata.Snapshot, id int) (any, *APIError)
+
+// batchResolvers lists the kinds a batch request may ask for
+var batchResolvers = map[string]batchResolver{
+	"card": func(snap *masterdata.Snapshot, id int) (any, *APIError) {
+		card, ok := snap.CardByID[id]
+		if !ok {
+			return nil, errNotFound("Card")
+		}
+		return buildCardDetail(snap, card), nil
+	},
+	"cardCostumes": func(snap *masterdata.Snapshot, id int) (any, *APIError) {
+		if _, ok := snap.CardByID[id]; !ok {
+			return nil, errNotFound("Card")
+		}
+		return snap.CardCostumes(id), nil
+	},
+	"event": func(snap *masterdata.Snapshot, id int) (any, *APIError) {
+		event, ok := snap.EventByID[id]
+		if !ok {
+			return nil, errNotFound("Event")
+		}
+		return buildEventDetail(snap, event), nil
+	},
+	"gacha": func(snap *masterdata.Snapshot, id int) (any, *APIError) {
+		gacha, ok := snap.GachaByID[id]
+		if !ok {
+			return nil, errNotFound("Gacha")
+		}
+		return buildGachaDetail(snap, gacha), nil
+	},
+	"music": func(snap *masterdata.Snapshot, id int) (any, *APIError) {
+		music, ok := snap.MusicByID[id]
+		if !ok {
+			return nil, errNotFound("Music")
+		}
+		return buildMusicDetail(snap, music), nil
+	},
+}
+
+func batchKinds() []string {
+	kinds := make([]string, 0, len(batchResolvers))
+	for kind := range batchResolvers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// validateBatch rejects unknown kinds and oversized requests before any lookup
+func validateBatch(req BatchRequest) error {
+	total := 0
+	for kind, ids := range req {
+		if _, ok := batchResolvers[kind]; !ok {
+			err := newAPIError(http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Unknown kind: %q", kind))
+			err.Details = map[string]any{"kind": kind, "allowed": batchKinds()}
+			return err
+		}
+		total += len(ids)
+	}
+	if total > maxBatchIDs {
+		return newAPIError(http.StatusBadRequest, CodeInvalidParameter,
+			fmt.Sprintf("Too many IDs: %d, at most %d per request", total, maxBatchIDs))
+	}
+	return nil
+}
+
+// resolveBatch looks up every requested ID in snap
+func resolveBatch(snap *masterdata.Snapshot, req BatchRequest) map[string]map[int]BatchItem {
+	results := make(map[string]map[int]BatchItem, len(req))
+	for kind, ids := range req {
+		resolve := batchResolvers[kind]
+		items := make(map[int]BatchItem, len(ids))
+		for _, id := range ids {
+			data, err := resolve(snap, id)
+			items[id] = BatchItem{Data: data, Error: err}
+		}
+		results[kind] = items
+	}
+	return results
+}
+
+func (h *Handler) handleBatch(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeBadRequest, "Invalid batch request: "+err.Error()))
+		return
+	}
+	if err := validateBatch(req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := BatchResponse{
+		Region:  snap.VersionInfo.Region,
+		Version: snap.VersionInfo.Version,
+		Results: resolveBatch(snap, req),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleCardCostumesBatch serves GET /api/v1/cards/costumes?ids=1,2,3
+func (h *Handler) handleCardCostumesBatch(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	ids, err := parseIntList(r.URL.Query(), "ids")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(ids) == 0 {
+		writeError(w, errMissingParam("ids"))
+		return
+	}
+	req := BatchRequest{"cardCostumes": ids}
+	if err := validateBatch(req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resolveBatch(snap, req)["cardCostumes"])
+}
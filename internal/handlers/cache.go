@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"snowy_viewer/internal/masterdata"
+)
+
+// notModified sets the snapshot's ETag and Last-Modified on w and reports
+// whether the request's conditional headers still match, in which case a
+// 304 has been written. If-None-Match takes precedence over If-Modified-Since.
+func notModified(w http.ResponseWriter, r *http.Request, snap *masterdata.Snapshot) bool {
+	if snap.ETag == "" {
+		return false
+	}
+	w.Header().Set("ETag", snap.ETag)
+	w.Header().Set("Last-Modified", snap.ModifiedAt.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, snap.ETag) {
+			return false
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil || snap.ModifiedAt.After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches performs the weak comparison of If-None-Match against etag
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header lists coding
+// without a zero quality
+func acceptsEncoding(header, coding string) bool {
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err != nil || v > 0
+	}
+	return false
+}
+
+// writeEncoded writes a body pre-encoded on the snapshot, brotli or gzip
+// compressed when the client accepts it. It encodes fallback instead if the
+// body is missing.
+func writeEncoded(w http.ResponseWriter, r *http.Request, snap *masterdata.Snapshot, name string, fallback any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoded, ok := snap.Encoded(name)
+	if !ok {
+		json.NewEncoder(w).Encode(fallback)
+		return
+	}
+
+	body := encoded.Raw
+	w.Header().Add("Vary", "Accept-Encoding")
+	switch accept := r.Header.Get("Accept-Encoding"); {
+	case acceptsEncoding(accept, "br"):
+		w.Header().Set("Content-Encoding", "br")
+		body = encoded.Brotli
+	case acceptsEncoding(accept, "gzip"):
+		w.Header().Set("Content-Encoding", "gzip")
+		body = encoded.Gzip
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
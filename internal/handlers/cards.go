@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// cardFilter holds the parsed /api/cards filter parameters
+type cardFilter struct {
+	search      string
+	searchIDs   map[int]bool
+	characters  map[int]bool
+	units       map[string]bool
+	rarities    map[string]bool
+	attrs       map[string]bool
+	releaseFrom int64
+	releaseTo   int64
+	hasFrom     bool
+	hasTo       bool
+	limited     *bool
+	event       *bool
+}
+
+// normalizeRarity accepts "4", "rarity_4" or "birthday"
+func normalizeRarity(v string) string {
+	if _, err := strconv.Atoi(v); err == nil {
+		return "rarity_" + v
+	}
+	if !strings.HasPrefix(v, "rarity_") {
+		return "rarity_" + v
+	}
+	return v
+}
+
+func parseCardFilter(r *http.Request) (*cardFilter, error) {
+	query := r.URL.Query()
+	f := &cardFilter{
+		search: query.Get("search"),
+		units:  toSet(parseList(query, "unit")),
+		attrs:  toSet(parseList(query, "attr")),
+	}
+
+	characters, err := parseIntList(query, "character")
+	if err != nil {
+		return nil, err
+	}
+	f.characters = toSet(characters)
+
+	var rarities []string
+	for _, v := range parseList(query, "rarity") {
+		rarities = append(rarities, normalizeRarity(v))
+	}
+	f.rarities = toSet(rarities)
+
+	if f.releaseFrom, f.hasFrom, err = parseInt64(query, "releaseFrom"); err != nil {
+		return nil, err
+	}
+	if f.releaseTo, f.hasTo, err = parseInt64(query, "releaseTo"); err != nil {
+		return nil, err
+	}
+	if f.limited, err = parseOptionalBool(query, "limited"); err != nil {
+		return nil, err
+	}
+	if f.event, err = parseOptionalBool(query, "event"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *cardFilter) match(snap *masterdata.Snapshot, c models.Card) bool {
+	if f.searchIDs != nil && !f.searchIDs[c.ID] {
+		return false
+	}
+	if f.characters != nil && !f.characters[c.CharacterID] {
+		return false
+	}
+	if f.units != nil {
+		found := false
+		for _, unit := range snap.CardUnits(c) {
+			if f.units[unit] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.rarities != nil && !f.rarities[c.CardRarityType] {
+		return false
+	}
+	if f.attrs != nil && !f.attrs[c.Attr] {
+		return false
+	}
+	if f.hasFrom && c.ReleaseAt < f.releaseFrom {
+		return false
+	}
+	if f.hasTo && c.ReleaseAt > f.releaseTo {
+		return false
+	}
+	if f.limited != nil && snap.IsLimitedCard(c) != *f.limited {
+		return false
+	}
+	if f.event != nil {
+		_, isEvent := snap.CardEventMap[c.ID]
+		if isEvent != *f.event {
+			return false
+		}
+	}
+	return true
+}
+
+// rarityRank orders rarities for sorting, birthday ranking with 4*
+var rarityRank = map[string]int{
+	"rarity_1":        1,
+	"rarity_2":        2,
+	"rarity_3":        3,
+	"rarity_4":        4,
+	"rarity_birthday": 4,
+}
+
+func (h *Handler) handleCardList(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Params
+	query := r.URL.Query()
+	page, limit := parsePaging(query)
+	sortBy := query.Get("sortBy")
+	sortOrder := query.Get("sortOrder")
+	filter, err := parseCardFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	proj, err := parseProjection(query, models.CardListItem{}, cardRelations...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filter.searchIDs = snap.Text.MatchIDs(masterdata.DocCard, filter.search)
+
+	// Filter
+	var filtered []models.Card
+	for _, c := range masterdata.Cards.Rows(snap) {
+		if filter.match(snap, c) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	// Sort
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		var less bool
+		switch sortBy {
+		case "releaseAt":
+			less = a.ReleaseAt < b.ReleaseAt || (a.ReleaseAt == b.ReleaseAt && a.ID < b.ID)
+		case "rarity":
+			ra, rb := rarityRank[a.CardRarityType], rarityRank[b.CardRarityType]
+			less = ra < rb || (ra == rb && a.ID < b.ID)
+		default:
+			less = a.ID < b.ID
+		}
+		if sortOrder == "asc" {
+			return less
+		}
+		return !less
+	})
+
+	// Paginate
+	total := len(filtered)
+	start, end := pageBounds(total, page, limit)
+	paged := filtered[start:end]
+
+	// Map to Response
+	resultItems := make([]models.CardListItem, len(paged))
+	for i, c := range paged {
+		resultItems[i] = models.CardListItem{
+			Card:           c,
+			CardSupplyType: snap.CardSupplyTypeByID[c.CardSupplyID],
+		}
+		if len(proj.includes) > 0 {
+			detail := buildCardDetail(snap, c)
+			if proj.has("character") {
+				resultItems[i].Character = detail.Character
+			}
+			if proj.has("skill") {
+				resultItems[i].Skill = detail.Skill
+			}
+			if proj.has("event") {
+				resultItems[i].Event = detail.Event
+			}
+			if proj.has("gachas") {
+				resultItems[i].Gachas = detail.Gachas
+			}
+			if proj.has("costumes") {
+				resultItems[i].Costumes = detail.Costumes
+			}
+		}
+	}
+
+	resp := models.CardListResponse{
+		Total: total,
+		Page:  page,
+		Limit: limit,
+		Cards: resultItems,
+	}
+
+	proj.write(w, resp, "cards")
+}
+
+func (h *Handler) handleCardDetail(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	card, found := snap.CardByID[id]
+	if !found {
+		writeError(w, errNotFound("Card"))
+		return
+	}
+	proj, err := parseProjection(r.URL.Query(), models.CardDetailResponse{}, cardRelations...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	detail := buildCardDetail(snap, card)
+	resp := models.CardDetailResponse{Card: detail.Card, CardSupplyType: detail.CardSupplyType}
+	if proj.has("character") {
+		resp.Character = detail.Character
+	}
+	if proj.has("skill") {
+		resp.Skill = detail.Skill
+	}
+	if proj.has("event") {
+		resp.Event = detail.Event
+	}
+	if proj.has("gachas") {
+		resp.Gachas = detail.Gachas
+	}
+	if proj.has("costumes") {
+		resp.Costumes = detail.Costumes
+	}
+
+	proj.write(w, resp, "")
+}
+
+// cardRelations are the include= values of the card endpoints
+var cardRelations = []string{"character", "skill", "event", "gachas", "costumes"}
+
+// buildCardDetail joins a card with its character, skill, event, gachas and costumes
+func buildCardDetail(snap *masterdata.Snapshot, card models.Card) models.CardDetailResponse {
+	resp := models.CardDetailResponse{
+		Card:           card,
+		CardSupplyType: snap.CardSupplyTypeByID[card.CardSupplyID],
+		Gachas:         snap.CardGachaMap[card.ID],
+		Costumes:       snap.CardCostumes(card.ID),
+	}
+	if ch, ok := snap.CharacterByID[card.CharacterID]; ok {
+		resp.Character = &ch
+	}
+	if sk, ok := snap.SkillByID[card.SkillID]; ok {
+		resp.Skill = &sk
+	}
+	if ev, ok := snap.CardEventMap[card.ID]; ok {
+		resp.Event = &ev
+	}
+	if resp.Gachas == nil {
+		resp.Gachas = []models.GachaInfo{}
+	}
+	return resp
+}
+
+// limitedGachaTypes and permanentGachaTypes classify appearances of cards
+// without a known supply type. Ceil gachas feature limited and permanent
+// cards alike, so they are in neither.
+var (
+	limitedGachaTypes = map[string]bool{
+		"limited":           true,
+		"birthday":          true,
+		"colorful_festival": true,
+	}
+	permanentGachaTypes = map[string]bool{
+		"normal": true,
+	}
+)
+
+// gachaAvailability tells whether a card is featured as a limited or a
+// permanent card, by its supply type when known and else by the gacha type
+func gachaAvailability(snap *masterdata.Snapshot, card models.Card, gachaType string) string {
+	switch {
+	case snap.CardSupplyTypeByID[card.CardSupplyID] != "":
+		if snap.IsLimitedCard(card) {
+			return models.AvailabilityLimited
+		}
+		return models.AvailabilityPermanent
+	case limitedGachaTypes[gachaType]:
+		return models.AvailabilityLimited
+	case permanentGachaTypes[gachaType]:
+		return models.AvailabilityPermanent
+	}
+	return models.AvailabilityUnknown
+}
+
+func (h *Handler) handleCardGachaHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	card, found := snap.CardByID[id]
+	if !found {
+		writeError(w, errNotFound("Card"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildCardGachaHistory(snap, card))
+}
+
+// buildCardGachaHistory orders the gachas featuring a card and groups them
+// into runs: the debut, then one rerun for each appearance starting after
+// every earlier one has ended
+func buildCardGachaHistory(snap *masterdata.Snapshot, card models.Card) models.CardGachaHistoryResponse {
+	resp := models.CardGachaHistoryResponse{
+		CardID:         card.ID,
+		CardRarityType: card.CardRarityType,
+		CardSupplyType: snap.CardSupplyTypeByID[card.CardSupplyID],
+		Limited:        snap.IsLimitedCard(card),
+		Appearances:    []models.CardGachaAppearance{},
+	}
+
+	seen := make(map[int]bool)
+	var gachas []models.Gacha
+	for _, info := range snap.CardGachaMap[card.ID] {
+		if g, ok := snap.GachaByID[info.ID]; ok && !seen[g.ID] {
+			seen[g.ID] = true
+			gachas = append(gachas, g)
+		}
+	}
+	sort.Slice(gachas, func(i, j int) bool {
+		if gachas[i].StartAt != gachas[j].StartAt {
+			return gachas[i].StartAt < gachas[j].StartAt
+		}
+		return gachas[i].ID < gachas[j].ID
+	})
+
+	run, runEnd := -1, int64(0)
+	var intervals []int64
+	for _, g := range gachas {
+		a := models.CardGachaAppearance{
+			GachaInfo:    models.GachaInfo{ID: g.ID, Name: g.Name, AssetbundleName: g.AssetbundleName},
+			GachaType:    g.GachaType,
+			StartAt:      g.StartAt,
+			EndAt:        g.EndAt,
+			Availability: gachaAvailability(snap, card, g.GachaType),
+		}
+		if run < 0 || g.StartAt > runEnd {
+			if run >= 0 {
+				a.IntervalMs = g.StartAt - runEnd
+				intervals = append(intervals, a.IntervalMs)
+			}
+			run++
+			runEnd = g.EndAt
+		} else {
+			runEnd = max(runEnd, g.EndAt)
+		}
+		a.Run = run
+		a.Kind = "rerun"
+		if run == 0 {
+			a.Kind = "debut"
+		}
+		resp.Appearances = append(resp.Appearances, a)
+	}
+
+	if len(gachas) > 0 {
+		resp.DebutAt = gachas[0].StartAt
+		resp.LastAt = gachas[len(gachas)-1].StartAt
+		resp.RerunCount = run
+		resp.NeverRerun = run == 0
+	}
+	if len(intervals) > 0 {
+		var sum int64
+		resp.MinIntervalMs, resp.MaxIntervalMs = intervals[0], intervals[0]
+		for _, iv := range intervals {
+			sum += iv
+			resp.MinIntervalMs = min(resp.MinIntervalMs, iv)
+			resp.MaxIntervalMs = max(resp.MaxIntervalMs, iv)
+		}
+		resp.AverageIntervalMs = sum / int64(len(intervals))
+	}
+	return resp
+}
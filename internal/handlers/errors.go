@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes carried in APIError.Code
+const (
+	CodeBadRequest       = "bad_request"
+	CodeMissingParameter = "missing_parameter"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeUnknownRegion    = "unknown_region"
+	CodeRegionNotLoaded  = "region_not_loaded"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUpstream         = "upstream_error"
+	CodeInternal         = "internal_error"
+)
+
+// APIError is the error every handler reports, written as {"error": {...}}
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// ErrorResponse is the JSON envelope of an APIError
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+func newAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// errNotFound reports a missing resource, e.g. errNotFound("Card")
+func errNotFound(resource string) *APIError {
+	return newAPIError(http.StatusNotFound, CodeNotFound, resource+" not found")
+}
+
+func errMissingParam(key string) *APIError {
+	err := newAPIError(http.StatusBadRequest, CodeMissingParameter, fmt.Sprintf("Missing %s parameter", key))
+	err.Details = map[string]string{"parameter": key}
+	return err
+}
+
+func errInvalidParam(key, value string) *APIError {
+	err := newAPIError(http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid %s: %q", key, value))
+	err.Details = map[string]string{"parameter": key, "value": value}
+	return err
+}
+
+// errUpstream wraps a failure reported by an upstream service with its status
+func errUpstream(status int, err error) *APIError {
+	code := CodeUpstream
+	switch {
+	case status == http.StatusBadRequest:
+		code = CodeBadRequest
+	case status == http.StatusNotFound:
+		code = CodeNotFound
+	case status < 400:
+		status = http.StatusBadGateway
+	}
+	return newAPIError(status, code, err.Error())
+}
+
+// writeError writes err as an ErrorResponse. Errors other than APIError
+// become a 500 internal_error.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = newAPIError(http.StatusInternalServerError, CodeInternal, err.Error())
+	}
+	h := w.Header()
+	h.Del("Content-Encoding")
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: apiErr})
+}
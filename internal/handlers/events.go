@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// eventFilter holds the parsed /api/events filter parameters
+type eventFilter struct {
+	search    string
+	searchIDs map[int]bool
+	types     map[string]bool
+	units     map[string]bool
+	from      int64
+	to        int64
+	hasFrom   bool
+	hasTo     bool
+}
+
+func parseEventFilter(r *http.Request) (*eventFilter, error) {
+	query := r.URL.Query()
+	f := &eventFilter{
+		search: query.Get("search"),
+		types:  toSet(parseList(query, "type")),
+		units:  toSet(parseList(query, "unit")),
+	}
+	var err error
+	if f.from, f.hasFrom, err = parseInt64(query, "from"); err != nil {
+		return nil, err
+	}
+	if f.to, f.hasTo, err = parseInt64(query, "to"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// match keeps events of the requested types and units whose
+// startAt..closedAt period overlaps the from..to window
+func (f *eventFilter) match(e models.Event) bool {
+	if f.searchIDs != nil && !f.searchIDs[e.ID] {
+		return false
+	}
+	if f.types != nil && !f.types[e.EventType] {
+		return false
+	}
+	if f.units != nil && !f.units[e.Unit] {
+		return false
+	}
+	if f.hasFrom && e.ClosedAt < f.from {
+		return false
+	}
+	if f.hasTo && e.StartAt > f.to {
+		return false
+	}
+	return true
+}
+
+func (h *Handler) handleEventList(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Params
+	query := r.URL.Query()
+	page, limit := parsePaging(query)
+	sortBy := query.Get("sortBy")
+	sortOrder := query.Get("sortOrder")
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	proj, err := parseProjection(query, models.EventListItem{}, eventRelations...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Filter
+	var filtered []models.Event
+	filter.searchIDs = snap.Text.MatchIDs(masterdata.DocEvent, filter.search)
+	for _, e := range masterdata.Events.Rows(snap) {
+		if filter.match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+
+	// Sort
+	sort.SliceStable(filtered, func(i, j int) bool {
+		var less bool
+		if sortBy == "id" {
+			less = filtered[i].ID < filtered[j].ID
+		} else {
+			less = filtered[i].StartAt < filtered[j].StartAt
+		}
+		if sortOrder == "asc" {
+			return less
+		}
+		return !less
+	})
+
+	// Paginate
+	total := len(filtered)
+	start, end := pageBounds(total, page, limit)
+	paged := filtered[start:end]
+
+	// Map to Response
+	resultItems := make([]models.EventListItem, len(paged))
+	for i, e := range paged {
+		resultItems[i] = models.EventListItem{
+			ID:              e.ID,
+			EventType:       e.EventType,
+			Name:            e.Name,
+			AssetbundleName: e.AssetbundleName,
+			Unit:            e.Unit,
+			StartAt:         e.StartAt,
+			AggregateAt:     e.AggregateAt,
+			ClosedAt:        e.ClosedAt,
+			VirtualLiveId:   e.VirtualLiveId,
+		}
+		if len(proj.includes) > 0 {
+			detail := buildEventDetail(snap, e)
+			if proj.has("cards") {
+				resultItems[i].Cards = detail.Cards
+			}
+			if proj.has("musics") {
+				resultItems[i].Musics = detail.Musics
+			}
+			if proj.has("virtualLive") {
+				resultItems[i].VirtualLive = detail.VirtualLive
+			}
+		}
+	}
+
+	resp := models.EventListResponse{
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+		Events: resultItems,
+	}
+
+	proj.write(w, resp, "events")
+}
+
+func (h *Handler) handleEventDetail(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	event, found := snap.EventByID[id]
+	if !found {
+		writeError(w, errNotFound("Event"))
+		return
+	}
+	proj, err := parseProjection(r.URL.Query(), models.EventDetailResponse{}, eventRelations...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	detail := buildEventDetail(snap, event)
+	resp := models.EventDetailResponse{Event: detail.Event}
+	if proj.has("cards") {
+		resp.Cards = detail.Cards
+	}
+	if proj.has("musics") {
+		resp.Musics = detail.Musics
+	}
+	if proj.has("virtualLive") {
+		resp.VirtualLive = detail.VirtualLive
+	}
+
+	proj.write(w, resp, "")
+}
+
+// eventRelations are the include= values of the event endpoints
+var eventRelations = []string{"cards", "musics", "virtualLive"}
+
+// buildEventDetail joins an event with its bonus cards, musics and virtual live
+func buildEventDetail(snap *masterdata.Snapshot, event models.Event) models.EventDetailResponse {
+	resp := models.EventDetailResponse{
+		Event:  event,
+		Cards:  []models.EventCardItem{},
+		Musics: []models.EventMusicItem{},
+	}
+	if resp.EventRankingRewardRanges == nil {
+		resp.EventRankingRewardRanges = []models.EventRankingRewardRange{}
+	}
+
+	for _, ec := range snap.EventCardsByEvent[event.ID] {
+		item := models.EventCardItem{
+			CardID:          ec.CardID,
+			BonusRate:       ec.BonusRate,
+			LeaderBonusRate: ec.LeaderBonusRate,
+		}
+		if card, ok := snap.CardByID[ec.CardID]; ok {
+			item.Card = &card
+		}
+		resp.Cards = append(resp.Cards, item)
+	}
+
+	for _, em := range snap.EventMusicsByEvent[event.ID] {
+		item := models.EventMusicItem{MusicID: em.MusicID, Seq: em.Seq}
+		if music, ok := snap.MusicByID[em.MusicID]; ok {
+			item.Music = &music
+		}
+		resp.Musics = append(resp.Musics, item)
+	}
+
+	if vl, ok := snap.EventVirtualLiveMap[event.ID]; ok {
+		resp.VirtualLive = &vl
+	}
+	return resp
+}
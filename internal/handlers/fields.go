@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// projection holds the fields= and include= parameters of an endpoint
+type projection struct {
+	fields   []string
+	includes map[string]bool
+}
+
+// modelFields caches the JSON field names of response models by type
+var modelFields sync.Map
+
+// jsonFieldSet returns the top-level JSON field names of a struct type
+func jsonFieldSet(t reflect.Type) map[string]bool {
+	if cached, ok := modelFields.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+	b := &schemaBuilder{components: make(map[string]any)}
+	props := make(map[string]any)
+	b.addFields(t, props, make(map[string]bool), false)
+	set := make(map[string]bool, len(props))
+	for name := range props {
+		set[name] = true
+	}
+	modelFields.Store(t, set)
+	return set
+}
+
+// parseProjection validates fields= against the JSON fields of model and
+// include= against the relations the endpoint can embed
+func parseProjection(query url.Values, model any, relations ...string) (*projection, error) {
+	known := jsonFieldSet(reflect.TypeOf(model))
+	p := &projection{fields: parseList(query, "fields")}
+	for _, f := range p.fields {
+		if !known[f] {
+			err := errInvalidParam("fields", f)
+			err.Details = map[string]any{"parameter": "fields", "value": f, "allowed": sortedKeys(known)}
+			return nil, err
+		}
+	}
+
+	allowed := toSet(relations)
+	for _, inc := range parseList(query, "include") {
+		if !allowed[inc] {
+			err := errInvalidParam("include", inc)
+			err.Details = map[string]any{"parameter": "include", "value": inc, "allowed": relations}
+			return nil, err
+		}
+		if p.includes == nil {
+			p.includes = make(map[string]bool)
+		}
+		p.includes[inc] = true
+	}
+	return p, nil
+}
+
+// has reports whether a relation was requested with include=
+func (p *projection) has(relation string) bool {
+	return p.includes[relation]
+}
+
+// write encodes v, keeping only the selected fields of v itself when listKey
+// is empty, or of every item in the v[listKey] array otherwise
+func (p *projection) write(w http.ResponseWriter, v any, listKey string) {
+	w.Header().Set("Content-Type", "application/json")
+	if len(p.fields) == 0 {
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var doc map[string]json.RawMessage
+	json.Unmarshal(raw, &doc)
+	if listKey == "" {
+		json.NewEncoder(w).Encode(p.selectFields(doc))
+		return
+	}
+
+	var items []map[string]json.RawMessage
+	json.Unmarshal(doc[listKey], &items)
+	selected := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		selected[i] = p.selectFields(item)
+	}
+	out := make(map[string]any, len(doc))
+	for k, v := range doc {
+		out[k] = v
+	}
+	out[listKey] = selected
+	json.NewEncoder(w).Encode(out)
+}
+
+// selectFields keeps the requested fields plus any embedded relations
+func (p *projection) selectFields(item map[string]json.RawMessage) map[string]json.RawMessage {
+	selected := make(map[string]json.RawMessage, len(p.fields)+len(p.includes))
+	for _, f := range p.fields {
+		if v, ok := item[f]; ok {
+			selected[f] = v
+		}
+	}
+	for inc := range p.includes {
+		if v, ok := item[inc]; ok {
+			selected[inc] = v
+		}
+	}
+	return selected
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
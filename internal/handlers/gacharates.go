@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// gachaPool is the card pool of a gacha grouped by rarity
+type gachaPool struct {
+	// rarities lists every rarity with a rate or a card, highest first
+	rarities []string
+	cards    map[string][]models.GachaDetail
+	weights  map[string]int
+	pickups  map[int]bool
+	rates    map[string]float64
+}
+
+func newGachaPool(snap *masterdata.Snapshot, gacha models.Gacha) *gachaPool {
+	pool := &gachaPool{
+		cards:   make(map[string][]models.GachaDetail),
+		weights: make(map[string]int),
+		pickups: toSet(snap.GachaPickups[gacha.ID]),
+		rates:   make(map[string]float64),
+	}
+	seen := make(map[string]bool)
+	addRarity := func(rarity string) {
+		if !seen[rarity] {
+			seen[rarity] = true
+			pool.rarities = append(pool.rarities, rarity)
+		}
+	}
+	for _, rr := range gacha.GachaCardRarityRates {
+		pool.rates[rr.CardRarityType] += rr.Rate
+		addRarity(rr.CardRarityType)
+	}
+	for _, d := range gacha.GachaDetails {
+		card, ok := snap.CardByID[d.CardID]
+		if !ok {
+			continue
+		}
+		rarity := card.CardRarityType
+		pool.cards[rarity] = append(pool.cards[rarity], d)
+		pool.weights[rarity] += d.Weight
+		addRarity(rarity)
+	}
+	sort.SliceStable(pool.rarities, func(i, j int) bool {
+		ri, rj := pool.rarities[i], pool.rarities[j]
+		if rarityRank[ri] != rarityRank[rj] {
+			return rarityRank[ri] > rarityRank[rj]
+		}
+		return ri < rj
+	})
+	return pool
+}
+
+// guaranteedRarity parses over_rarity_N_once behaviors into rarity_N
+func guaranteedRarity(behaviorType string) (string, bool) {
+	n, ok := strings.CutPrefix(behaviorType, "over_rarity_")
+	if !ok {
+		return "", false
+	}
+	if n, ok = strings.CutSuffix(n, "_once"); !ok {
+		return "", false
+	}
+	if _, err := strconv.Atoi(n); err != nil {
+		return "", false
+	}
+	return "rarity_" + n, true
+}
+
+// foldRates moves the rates of rarities below minRarity onto minRarity, as on
+// the guaranteed pull of a 10-pull
+func (p *gachaPool) foldRates(minRarity string) map[string]float64 {
+	folded := make(map[string]float64, len(p.rates))
+	for rarity, rate := range p.rates {
+		if rarityRank[rarity] < rarityRank[minRarity] {
+			folded[minRarity] += rate
+		} else {
+			folded[rarity] += rate
+		}
+	}
+	return folded
+}
+
+// cardRate is the percent chance of drawing d given per-rarity rates
+func (p *gachaPool) cardRate(rates map[string]float64, rarity string, d models.GachaDetail) float64 {
+	total := p.weights[rarity]
+	if total <= 0 {
+		return 0
+	}
+	return rates[rarity] * float64(d.Weight) / float64(total)
+}
+
+// odds computes rarity and card odds of a pull with the given rates
+func (p *gachaPool) odds(rates map[string]float64) models.GachaSlotOdds {
+	slot := models.GachaSlotOdds{
+		Rarities: []models.GachaRarityOdds{},
+		Cards:    []models.GachaCardOdds{},
+	}
+	for _, rarity := range p.rarities {
+		ro := models.GachaRarityOdds{
+			Rarity:      rarity,
+			Rate:        rates[rarity],
+			CardCount:   len(p.cards[rarity]),
+			TotalWeight: p.weights[rarity],
+		}
+		var pickups, offRate []models.GachaCardOdds
+		for _, d := range p.cards[rarity] {
+			co := models.GachaCardOdds{
+				CardID: d.CardID,
+				Rarity: rarity,
+				Weight: d.Weight,
+				Pickup: p.pickups[d.CardID],
+				Rate:   p.cardRate(rates, rarity, d),
+			}
+			if co.Pickup {
+				ro.PickupCount++
+				ro.PickupRate += co.Rate
+				pickups = append(pickups, co)
+			} else {
+				offRate = append(offRate, co)
+			}
+		}
+		// Off-rate cards share what the pickups leave of the rarity's rate
+		ro.OffRate = ro.Rate - ro.PickupRate
+		slot.Rarities = append(slot.Rarities, ro)
+		slot.Cards = append(slot.Cards, pickups...)
+		slot.Cards = append(slot.Cards, offRate...)
+	}
+	return slot
+}
+
+// buildGachaRates computes the odds of a single pull and of the guaranteed
+// pull of every behavior with a minimum rarity
+func buildGachaRates(snap *masterdata.Snapshot, gacha models.Gacha) models.GachaRatesResponse {
+	pool := newGachaPool(snap, gacha)
+	resp := models.GachaRatesResponse{
+		GachaID:   gacha.ID,
+		Name:      gacha.Name,
+		GachaType: gacha.GachaType,
+		Rates:     pool.odds(pool.rates),
+		Behaviors: []models.GachaBehaviorOdds{},
+	}
+	for _, b := range gacha.GachaBehaviors {
+		bo := models.GachaBehaviorOdds{GachaBehavior: b}
+		if rarity, ok := guaranteedRarity(b.GachaBehaviorType); ok {
+			guaranteed := pool.odds(pool.foldRates(rarity))
+			bo.GuaranteedRarity = rarity
+			bo.Guaranteed = &guaranteed
+		}
+		resp.Behaviors = append(resp.Behaviors, bo)
+	}
+	return resp
+}
+
+func (h *Handler) handleGachaRates(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	gacha, found := snap.GachaByID[id]
+	if !found {
+		writeError(w, errNotFound("Gacha"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildGachaRates(snap, gacha))
+}
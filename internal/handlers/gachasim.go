@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// Simulation limits
+const (
+	defaultSimulatedPulls   = 10
+	maxSimulatedPulls       = 3000
+	defaultMonteCarloTrials = 10000
+	maxMonteCarloTrials     = 50000
+	// monteCarloPullCap bounds a trial when the target cannot be sparked
+	monteCarloPullCap    = 1000
+	monteCarloBucketSize = 10
+	// monteCarloPullBudget bounds the pulls of one Monte Carlo request; trials
+	// are reduced so that trials times the pulls per trial stay within it
+	monteCarloPullBudget = 5_000_000
+	// monteCarloCheckEvery is how many trials run between cancellation checks
+	monteCarloCheckEvery = 100
+	// gachaSparkPulls is the number of ceiling points, one per pull, a pickup
+	// card can be exchanged for on gachas with a ceiling item
+	gachaSparkPulls = 300
+	// maxSeed keeps generated seeds exact as JSON numbers in JavaScript
+	maxSeed = 1 << 53
+)
+
+// rarityChance is the rate of a rarity that has cards to draw
+type rarityChance struct {
+	rarity string
+	rate   float64
+}
+
+// gachaSampler draws cards from a pool, using the folded rates on the last
+// pull of every guaranteed spin
+type gachaSampler struct {
+	pool       *gachaPool
+	normal     []rarityChance
+	guaranteed []rarityChance
+	spinCount  int
+	// cumulative card weights per rarity, for binary search
+	cumulative map[string][]int
+}
+
+func (p *gachaPool) chances(rates map[string]float64) []rarityChance {
+	var chances []rarityChance
+	for _, rarity := range p.rarities {
+		if rates[rarity] > 0 && p.weights[rarity] > 0 {
+			chances = append(chances, rarityChance{rarity, rates[rarity]})
+		}
+	}
+	return chances
+}
+
+func newGachaSampler(pool *gachaPool, behavior *models.GachaBehavior) *gachaSampler {
+	s := &gachaSampler{
+		pool:       pool,
+		normal:     pool.chances(pool.rates),
+		cumulative: make(map[string][]int),
+	}
+	if behavior != nil && behavior.SpinCount > 1 {
+		if rarity, ok := guaranteedRarity(behavior.GachaBehaviorType); ok {
+			s.guaranteed = pool.chances(pool.foldRates(rarity))
+			s.spinCount = behavior.SpinCount
+		}
+	}
+	for rarity, cards := range pool.cards {
+		sums := make([]int, len(cards))
+		total := 0
+		for i, d := range cards {
+			total += max(d.Weight, 0)
+			sums[i] = total
+		}
+		s.cumulative[rarity] = sums
+	}
+	return s
+}
+
+// canDraw reports whether any rarity with a rate has cards
+func (s *gachaSampler) canDraw() bool {
+	return len(s.normal) > 0
+}
+
+// isGuaranteed reports whether the n-th pull (from 1) ends a guaranteed spin
+func (s *gachaSampler) isGuaranteed(n int) bool {
+	return s.spinCount > 0 && len(s.guaranteed) > 0 && n%s.spinCount == 0
+}
+
+// draw picks the card of the n-th pull and reports its rarity group
+func (s *gachaSampler) draw(rng *rand.Rand, n int) (models.GachaDetail, string, bool) {
+	guaranteed := s.isGuaranteed(n)
+	chances := s.normal
+	if guaranteed {
+		chances = s.guaranteed
+	}
+	total := 0.0
+	for _, c := range chances {
+		total += c.rate
+	}
+	x := rng.Float64() * total
+	rarity := chances[len(chances)-1].rarity
+	for _, c := range chances {
+		if x < c.rate {
+			rarity = c.rarity
+			break
+		}
+		x -= c.rate
+	}
+	sums := s.cumulative[rarity]
+	w := rng.IntN(sums[len(sums)-1])
+	i := sort.SearchInts(sums, w+1)
+	return s.pool.cards[rarity][i], rarity, guaranteed
+}
+
+// simulationBehavior picks the requested behavior, or by default the
+// guaranteed spin with the most pulls, falling back to the first behavior
+func simulationBehavior(gacha models.Gacha, id int) (*models.GachaBehavior, bool) {
+	var picked *models.GachaBehavior
+	for i := range gacha.GachaBehaviors {
+		b := &gacha.GachaBehaviors[i]
+		if id != 0 {
+			if b.ID == id {
+				return b, true
+			}
+			continue
+		}
+		_, guaranteed := guaranteedRarity(b.GachaBehaviorType)
+		if guaranteed && (picked == nil || b.SpinCount > picked.SpinCount) {
+			picked = b
+		}
+	}
+	if id != 0 {
+		return nil, false
+	}
+	if picked == nil && len(gacha.GachaBehaviors) > 0 {
+		picked = &gacha.GachaBehaviors[0]
+	}
+	return picked, true
+}
+
+// defaultTarget is the first pickup card of the highest rarity, or 0
+func (p *gachaPool) defaultTarget() int {
+	for _, rarity := range p.rarities {
+		for _, d := range p.cards[rarity] {
+			if p.pickups[d.CardID] {
+				return d.CardID
+			}
+		}
+	}
+	return 0
+}
+
+func (p *gachaPool) hasCard(cardID int) bool {
+	for _, cards := range p.cards {
+		for _, d := range cards {
+			if d.CardID == cardID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gachaSimulation holds the parsed simulate parameters
+type gachaSimulation struct {
+	mode     string
+	seed     int64
+	pulls    int
+	trials   int
+	target   int
+	behavior *models.GachaBehavior
+	// sparkPulls is 0 when the target cannot be exchanged
+	sparkPulls int
+}
+
+func parseGachaSimulation(r *http.Request, gacha models.Gacha, pool *gachaPool) (*gachaSimulation, error) {
+	query := r.URL.Query()
+	sim := &gachaSimulation{mode: query.Get("mode")}
+	switch sim.mode {
+	case "":
+		sim.mode = "single"
+	case "single", "montecarlo":
+	default:
+		return nil, errInvalidParam("mode", sim.mode)
+	}
+
+	seed, hasSeed, err := parseInt64(query, "seed")
+	if err != nil {
+		return nil, err
+	}
+	if !hasSeed {
+		seed = rand.Int64N(maxSeed)
+	}
+	sim.seed = seed
+
+	if sim.pulls, err = parseIntInRange(query, "pulls", defaultSimulatedPulls, 1, maxSimulatedPulls); err != nil {
+		return nil, err
+	}
+	if sim.trials, err = parseIntInRange(query, "trials", defaultMonteCarloTrials, 1, maxMonteCarloTrials); err != nil {
+		return nil, err
+	}
+
+	behaviorID, err := parseIntInRange(query, "behaviorId", 0, 1, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	var found bool
+	if sim.behavior, found = simulationBehavior(gacha, behaviorID); !found {
+		return nil, errInvalidParam("behaviorId", query.Get("behaviorId"))
+	}
+
+	sim.target = pool.defaultTarget()
+	if v := query.Get("target"); v != "" {
+		target, err := strconv.Atoi(v)
+		if err != nil || !pool.hasCard(target) {
+			return nil, errInvalidParam("target", v)
+		}
+		sim.target = target
+	}
+	if sim.mode == "montecarlo" && sim.target == 0 {
+		return nil, errMissingParam("target")
+	}
+
+	if gacha.GachaCeilItemID > 0 && pool.pickups[sim.target] {
+		sim.sparkPulls = gachaSparkPulls
+	}
+	return sim, nil
+}
+
+// runPulls simulates sim.pulls pulls, exchanging ceiling points for the
+// target once enough are collected and the target has not been pulled
+func (sim *gachaSimulation) runPulls(s *gachaSampler, rng *rand.Rand, resp *models.GachaSimulationResponse) {
+	summary := &models.GachaSimulationSummary{
+		RarityCounts: make(map[string]int),
+		PickupCounts: make(map[int]int),
+	}
+	if b := sim.behavior; b != nil && b.SpinCount > 0 {
+		summary.CostResourceType = b.CostResourceType
+		summary.CostResourceQuantity = sim.pulls * b.CostResourceQuantity / b.SpinCount
+	}
+	resp.Results = make([]models.GachaPullResult, 0, sim.pulls)
+	for n := 1; n <= sim.pulls; n++ {
+		d, rarity, guaranteed := s.draw(rng, n)
+		pickup := s.pool.pickups[d.CardID]
+		resp.Results = append(resp.Results, models.GachaPullResult{
+			Pull:       n,
+			CardID:     d.CardID,
+			Rarity:     rarity,
+			Pickup:     pickup,
+			Guaranteed: guaranteed,
+		})
+		summary.RarityCounts[rarity]++
+		if pickup {
+			summary.PickupCounts[d.CardID]++
+			if summary.FirstPickupPull == 0 {
+				summary.FirstPickupPull = n
+			}
+		}
+		if d.CardID == sim.target {
+			summary.TargetObtained = true
+		}
+
+		if sim.sparkPulls > 0 {
+			summary.CeilPoints++
+			if !summary.TargetObtained && summary.CeilPoints >= sim.sparkPulls {
+				summary.CeilPoints -= sim.sparkPulls
+				summary.TargetObtained = true
+				resp.Exchanges = append(resp.Exchanges, models.GachaExchange{Pull: n, CardID: sim.target})
+			}
+		}
+	}
+	resp.Pulls = sim.pulls
+	resp.Summary = summary
+}
+
+// runMonteCarlo draws until the target is pulled or sparked in every trial.
+// It stops early when ctx is cancelled.
+func (sim *gachaSimulation) runMonteCarlo(ctx context.Context, s *gachaSampler, rng *rand.Rand, resp *models.GachaSimulationResponse) error {
+	limit := monteCarloPullCap
+	if sim.sparkPulls > 0 {
+		limit = sim.sparkPulls
+	}
+	trials := min(sim.trials, monteCarloPullBudget/limit)
+	dist := &models.GachaPullDistribution{Trials: trials, MaxPulls: limit}
+	needed := make([]int, 0, trials)
+	for t := 0; t < trials; t++ {
+		if t%monteCarloCheckEvery == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		got := 0
+		for n := 1; n <= limit; n++ {
+			if d, _, _ := s.draw(rng, n); d.CardID == sim.target {
+				got = n
+				break
+			}
+		}
+		switch {
+		case got > 0:
+			needed = append(needed, got)
+		case sim.sparkPulls > 0:
+			dist.Exchanged++
+			needed = append(needed, limit)
+		default:
+			dist.NotObtained++
+		}
+	}
+
+	sort.Ints(needed)
+	if len(needed) > 0 {
+		sum := 0
+		for _, n := range needed {
+			sum += n
+		}
+		dist.Mean = float64(sum) / float64(len(needed))
+		dist.Median = percentile(needed, 0.5)
+		dist.P90 = percentile(needed, 0.9)
+		dist.P99 = percentile(needed, 0.99)
+	}
+
+	dist.Buckets = []models.GachaPullsBucket{}
+	i, cumulative := 0, 0
+	for from := 1; from <= limit; from += monteCarloBucketSize {
+		bucket := models.GachaPullsBucket{From: from, To: min(from+monteCarloBucketSize-1, limit)}
+		for i < len(needed) && needed[i] <= bucket.To {
+			bucket.Count++
+			i++
+		}
+		cumulative += bucket.Count
+		bucket.Cumulative = float64(cumulative) / float64(trials)
+		dist.Buckets = append(dist.Buckets, bucket)
+	}
+	resp.Distribution = dist
+	return nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int, p float64) int {
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func (h *Handler) handleGachaSimulate(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var snap *masterdata.Snapshot
+	if r.URL.Query().Get("seed") == "" {
+		// A generated seed gives a new result on every request, so snapshot
+		// validators cannot be used
+		store, ok := h.storeFor(w, r)
+		if !ok {
+			return
+		}
+		snap = store.Snapshot()
+	} else {
+		if snap, ok = h.snapshotFor(w, r); !ok {
+			return
+		}
+	}
+
+	gacha, found := snap.GachaByID[id]
+	if !found {
+		writeError(w, errNotFound("Gacha"))
+		return
+	}
+	resp, err := simulateGacha(snap, gacha, r)
+	if err != nil {
+		if r.Context().Err() != nil {
+			// The client is gone
+			return
+		}
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// simulateGacha runs the simulation requested by r. The same seed and
+// parameters always give the same result for the same master data.
+func simulateGacha(snap *masterdata.Snapshot, gacha models.Gacha, r *http.Request) (*models.GachaSimulationResponse, error) {
+	pool := newGachaPool(snap, gacha)
+	sim, err := parseGachaSimulation(r, gacha, pool)
+	if err != nil {
+		return nil, err
+	}
+	sampler := newGachaSampler(pool, sim.behavior)
+	if !sampler.canDraw() {
+		return nil, newAPIError(http.StatusUnprocessableEntity, CodeBadRequest, "Gacha has no drawable cards")
+	}
+
+	resp := &models.GachaSimulationResponse{
+		GachaID:      gacha.ID,
+		Mode:         sim.mode,
+		Seed:         sim.seed,
+		TargetCardID: sim.target,
+		SparkPulls:   sim.sparkPulls,
+	}
+	if sim.behavior != nil {
+		resp.BehaviorID = sim.behavior.ID
+	}
+	rng := rand.New(rand.NewPCG(uint64(sim.seed), 0))
+	if sim.mode == "montecarlo" {
+		if err := sim.runMonteCarlo(r.Context(), sampler, rng, resp); err != nil {
+			return nil, err
+		}
+	} else {
+		sim.runPulls(sampler, rng, resp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"snowy_viewer/internal/graphql"
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// GraphQL query limits
+const (
+	graphQLMaxDepth        = 8
+	graphQLMaxComplexity   = 10000
+	graphQLDefaultListSize = 10
+	graphQLMaxListSize     = 100
+)
+
+// snapshotKey carries the snapshot a query resolves against in the context
+type snapshotKey struct{}
+
+func gqlSnapshot(p graphql.ResolveParams) *masterdata.Snapshot {
+	return p.Context.Value(snapshotKey{}).(*masterdata.Snapshot)
+}
+
+// pageArgs are accepted by every list field
+var pageArgs = map[string]graphql.ArgType{
+	"limit":  graphql.Int,
+	"offset": graphql.Int,
+}
+
+// listArgs are accepted by every top-level list field
+var listArgs = withArgs(pageArgs, map[string]graphql.ArgType{
+	"ids":    graphql.IntList,
+	"search": graphql.String,
+})
+
+func withArgs(base map[string]graphql.ArgType, extra map[string]graphql.ArgType) map[string]graphql.ArgType {
+	args := make(map[string]graphql.ArgType, len(base)+len(extra))
+	for name, t := range base {
+		args[name] = t
+	}
+	for name, t := range extra {
+		args[name] = t
+	}
+	return args
+}
+
+var idArg = map[string]graphql.ArgType{"id": graphql.Int}
+
+// listWindow applies offset and limit to n items, clamping limit to
+// graphQLMaxListSize
+func listWindow(args graphql.Args, n int) (int, int) {
+	limit := args.Int("limit", graphQLDefaultListSize)
+	if limit < 1 {
+		limit = graphQLDefaultListSize
+	}
+	limit = min(limit, graphQLMaxListSize)
+	start := args.Int("offset", 0)
+	if start < 0 {
+		start = 0
+	}
+	if start > n {
+		start = n
+	}
+	return start, min(start+limit, n)
+}
+
+// window returns the rows selected by the limit and offset arguments
+func window[T any](args graphql.Args, rows []T) []T {
+	start, end := listWindow(args, len(rows))
+	return rows[start:end]
+}
+
+// listFilter selects rows by the ids and search arguments, in master order
+func listFilter[T any](p graphql.ResolveParams, docType string, rows []T, id func(T) int, match func(T) bool) []T {
+	var ids map[int]bool
+	if p.Args.Has("ids") {
+		ids = toSet(p.Args.Ints("ids"))
+	}
+	searchIDs := gqlSnapshot(p).Text.MatchIDs(docType, p.Args.String("search"))
+	result := []T{}
+	for _, row := range rows {
+		if ids != nil && !ids[id(row)] {
+			continue
+		}
+		if searchIDs != nil && !searchIDs[id(row)] {
+			continue
+		}
+		if match != nil && !match(row) {
+			continue
+		}
+		result = append(result, row)
+	}
+	return window(p.Args, result)
+}
+
+// graphQLSchema is the master data graph: cards link to their character,
+// skill, event, gachas and costumes, events to their cards, musics and
+// virtual live, and so on in both directions
+var graphQLSchema = newGraphQLSchema()
+
+func newGraphQLSchema() *graphql.Schema {
+	card := &graphql.Object{Name: "Card", Fields: graphql.ScalarFields(models.Card{})}
+	character := &graphql.Object{Name: "Character", Fields: graphql.ScalarFields(models.GameCharacter{})}
+	skill := &graphql.Object{Name: "Skill", Fields: graphql.ScalarFields(models.Skill{})}
+	event := &graphql.Object{Name: "Event", Fields: graphql.ScalarFields(models.Event{})}
+	eventCard := &graphql.Object{Name: "EventCard", Fields: graphql.ScalarFields(models.EventCard{})}
+	virtualLive := &graphql.Object{Name: "VirtualLive", Fields: graphql.ScalarFields(models.VirtualLive{})}
+	schedule := &graphql.Object{Name: "VirtualLiveSchedule", Fields: graphql.ScalarFields(models.VirtualLiveSchedule{})}
+	gacha := &graphql.Object{Name: "Gacha", Fields: graphql.ScalarFields(models.Gacha{})}
+	rarityRate := &graphql.Object{Name: "GachaRarityRate", Fields: graphql.ScalarFields(models.GachaCardRarityRate{})}
+	costume := &graphql.Object{Name: "Costume", Fields: graphql.ScalarFields(models.Costume3d{})}
+	costumeGroup := &graphql.Object{Name: "CostumeGroup", Fields: map[string]*graphql.Field{}}
+	music := &graphql.Object{Name: "Music", Fields: graphql.ScalarFields(models.Music{})}
+	difficulty := &graphql.Object{Name: "MusicDifficulty", Fields: graphql.ScalarFields(models.MusicDifficulty{})}
+	version := &graphql.Object{Name: "Version", Fields: graphql.ScalarFields(models.MasterVersionInfo{})}
+
+	// Card relations
+	card.Fields["character"] = &graphql.Field{Type: character, Resolve: func(p graphql.ResolveParams) (any, error) {
+		if ch, ok := gqlSnapshot(p).CharacterByID[p.Source.(models.Card).CharacterID]; ok {
+			return ch, nil
+		}
+		return nil, nil
+	}}
+	card.Fields["skill"] = &graphql.Field{Type: skill, Resolve: func(p graphql.ResolveParams) (any, error) {
+		if sk, ok := gqlSnapshot(p).SkillByID[p.Source.(models.Card).SkillID]; ok {
+			return sk, nil
+		}
+		return nil, nil
+	}}
+	card.Fields["event"] = &graphql.Field{Type: event, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		if info, ok := snap.CardEventMap[p.Source.(models.Card).ID]; ok {
+			if ev, ok := snap.EventByID[info.ID]; ok {
+				return ev, nil
+			}
+		}
+		return nil, nil
+	}}
+	card.Fields["gachas"] = &graphql.Field{Type: gacha, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		gachas := []models.Gacha{}
+		for _, info := range snap.CardGachaMap[p.Source.(models.Card).ID] {
+			if g, ok := snap.GachaByID[info.ID]; ok {
+				gachas = append(gachas, g)
+			}
+		}
+		return window(p.Args, gachas), nil
+	}}
+	card.Fields["costumes"] = &graphql.Field{Type: costume, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, gqlSnapshot(p).CardCostumes(p.Source.(models.Card).ID)), nil
+	}}
+	card.Fields["limited"] = &graphql.Field{Resolve: func(p graphql.ResolveParams) (any, error) {
+		return gqlSnapshot(p).IsLimitedCard(p.Source.(models.Card)), nil
+	}}
+
+	// Character relations
+	character.Fields["cards"] = &graphql.Field{Type: card, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		return window(p.Args, pickupCards(snap, snap.CardsByCharacter[p.Source.(models.GameCharacter).ID])), nil
+	}}
+
+	// Event relations
+	event.Fields["virtualLive"] = &graphql.Field{Type: virtualLive, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		if vl, ok := snap.VirtualLiveByID[p.Source.(models.Event).VirtualLiveId]; ok {
+			return vl, nil
+		}
+		return nil, nil
+	}}
+	event.Fields["cards"] = &graphql.Field{Type: eventCard, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, gqlSnapshot(p).EventCardsByEvent[p.Source.(models.Event).ID]), nil
+	}}
+	event.Fields["musics"] = &graphql.Field{Type: music, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		musics := []models.Music{}
+		for _, em := range snap.EventMusicsByEvent[p.Source.(models.Event).ID] {
+			if m, ok := snap.MusicByID[em.MusicID]; ok {
+				musics = append(musics, m)
+			}
+		}
+		return window(p.Args, musics), nil
+	}}
+	eventCard.Fields["card"] = &graphql.Field{Type: card, Resolve: func(p graphql.ResolveParams) (any, error) {
+		if c, ok := gqlSnapshot(p).CardByID[p.Source.(models.EventCard).CardID]; ok {
+			return c, nil
+		}
+		return nil, nil
+	}}
+	virtualLive.Fields["schedules"] = &graphql.Field{Type: schedule, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, p.Source.(models.VirtualLive).VirtualLiveSchedules), nil
+	}}
+	virtualLive.Fields["event"] = &graphql.Field{Type: event, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		if info, ok := snap.VirtualLiveEventMap[p.Source.(models.VirtualLive).ID]; ok {
+			if ev, ok := snap.EventByID[info.ID]; ok {
+				return ev, nil
+			}
+		}
+		return nil, nil
+	}}
+
+	// Gacha relations
+	gacha.Fields["pickups"] = &graphql.Field{Type: card, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		return window(p.Args, pickupCards(snap, snap.GachaPickups[p.Source.(models.Gacha).ID])), nil
+	}}
+	gacha.Fields["rarityRates"] = &graphql.Field{Type: rarityRate, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, p.Source.(models.Gacha).GachaCardRarityRates), nil
+	}}
+
+	// Costume groups
+	costume.Fields["group"] = &graphql.Field{Type: costumeGroup, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return p.Source.(models.Costume3d).Costume3dGroupId, nil
+	}}
+	costumeGroup.Fields["id"] = &graphql.Field{Resolve: func(p graphql.ResolveParams) (any, error) {
+		return p.Source.(int), nil
+	}}
+	costumeGroup.Fields["costumes"] = &graphql.Field{Type: costume, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, gqlSnapshot(p).Costume3dGroupMap[p.Source.(int)]), nil
+	}}
+
+	// Music relations
+	music.Fields["difficulties"] = &graphql.Field{Type: difficulty, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return window(p.Args, gqlSnapshot(p).MusicDifficultiesByMusic[p.Source.(models.Music).ID]), nil
+	}}
+	music.Fields["events"] = &graphql.Field{Type: event, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+		snap := gqlSnapshot(p)
+		events := []models.Event{}
+		for _, info := range snap.MusicEventMap[p.Source.(models.Music).ID] {
+			if ev, ok := snap.EventByID[info.ID]; ok {
+				events = append(events, ev)
+			}
+		}
+		return window(p.Args, events), nil
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"version": {Type: version, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return gqlSnapshot(p).VersionInfo, nil
+		}},
+		"card": {Type: card, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if c, ok := gqlSnapshot(p).CardByID[p.Args.Int("id", 0)]; ok {
+				return c, nil
+			}
+			return nil, nil
+		}},
+		"cards": {
+			Type: card, List: true,
+			Args: withArgs(listArgs, map[string]graphql.ArgType{
+				"character": graphql.IntList,
+				"unit":      graphql.StringList,
+				"rarity":    graphql.StringList,
+			}),
+			Description: "Cards in master order, filtered by character, unit and rarity",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				snap := gqlSnapshot(p)
+				f := &cardFilter{}
+				if p.Args.Has("character") {
+					f.characters = toSet(p.Args.Ints("character"))
+				}
+				if p.Args.Has("unit") {
+					f.units = toSet(p.Args.Strings("unit"))
+				}
+				if p.Args.Has("rarity") {
+					f.rarities = make(map[string]bool)
+					for _, r := range p.Args.Strings("rarity") {
+						f.rarities[normalizeRarity(r)] = true
+					}
+				}
+				return listFilter(p, masterdata.DocCard, masterdata.Cards.Rows(snap),
+					func(c models.Card) int { return c.ID },
+					func(c models.Card) bool { return f.match(snap, c) }), nil
+			},
+		},
+		"character": {Type: character, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if ch, ok := gqlSnapshot(p).CharacterByID[p.Args.Int("id", 0)]; ok {
+				return ch, nil
+			}
+			return nil, nil
+		}},
+		"characters": {Type: character, List: true, Args: pageArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return window(p.Args, masterdata.GameCharacters.Rows(gqlSnapshot(p))), nil
+		}},
+		"event": {Type: event, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if ev, ok := gqlSnapshot(p).EventByID[p.Args.Int("id", 0)]; ok {
+				return ev, nil
+			}
+			return nil, nil
+		}},
+		"events": {Type: event, List: true, Args: listArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return listFilter(p, masterdata.DocEvent, masterdata.Events.Rows(gqlSnapshot(p)),
+				func(e models.Event) int { return e.ID }, nil), nil
+		}},
+		"gacha": {Type: gacha, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if g, ok := gqlSnapshot(p).GachaByID[p.Args.Int("id", 0)]; ok {
+				return g, nil
+			}
+			return nil, nil
+		}},
+		"gachas": {Type: gacha, List: true, Args: listArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return listFilter(p, masterdata.DocGacha, masterdata.Gachas.Rows(gqlSnapshot(p)),
+				func(g models.Gacha) int { return g.ID }, nil), nil
+		}},
+		"music": {Type: music, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if m, ok := gqlSnapshot(p).MusicByID[p.Args.Int("id", 0)]; ok {
+				return m, nil
+			}
+			return nil, nil
+		}},
+		"musics": {Type: music, List: true, Args: listArgs, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return listFilter(p, masterdata.DocMusic, masterdata.Musics.Rows(gqlSnapshot(p)),
+				func(m models.Music) int { return m.ID }, nil), nil
+		}},
+		"virtualLive": {Type: virtualLive, Args: idArg, Resolve: func(p graphql.ResolveParams) (any, error) {
+			if vl, ok := gqlSnapshot(p).VirtualLiveByID[p.Args.Int("id", 0)]; ok {
+				return vl, nil
+			}
+			return nil, nil
+		}},
+	}}
+
+	return &graphql.Schema{
+		Query:           query,
+		MaxDepth:        graphQLMaxDepth,
+		MaxComplexity:   graphQLMaxComplexity,
+		DefaultListSize: graphQLDefaultListSize,
+	}
+}
+
+// handleGraphQL serves GraphQL queries over GET (?query=&variables=&operationName=)
+// and POST (JSON body). Every field resolves against the same snapshot.
+func (h *Handler) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Request
+	var req graphql.Request
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{
+				{Message: "Invalid GraphQL request: " + err.Error(), Extensions: map[string]string{"code": CodeBadRequest}},
+			}})
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if v := query.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{
+					{Message: "Invalid variables: " + err.Error(), Extensions: map[string]string{"code": CodeInvalidParameter}},
+				}})
+				return
+			}
+		}
+	}
+	if req.Query == "" {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{
+			{Message: "Missing required parameter: query", Extensions: map[string]string{"code": CodeMissingParameter}},
+		}})
+		return
+	}
+
+	// Execute
+	ctx := context.WithValue(r.Context(), snapshotKey{}, snap)
+	resp := graphQLSchema.Execute(ctx, req, nil)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeGraphQL(w, status, resp)
+}
+
+func writeGraphQL(w http.ResponseWriter, status int, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
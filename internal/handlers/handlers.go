@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"snowy_viewer/internal/bilibili"
 	"snowy_viewer/internal/masterdata"
@@ -14,86 +15,315 @@ import (
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
-	store    *masterdata.Store
+	stores   *masterdata.Stores
 	bilibili *bilibili.Client
+
+	// Generated OpenAPI document, built on first request
+	openAPIOnce sync.Once
+	openAPI     []byte
 }
 
 // New creates a new Handler instance
-func New(store *masterdata.Store, biliClient *bilibili.Client) *Handler {
+func New(stores *masterdata.Stores, biliClient *bilibili.Client) *Handler {
 	return &Handler{
-		store:    store,
+		stores:   stores,
 		bilibili: biliClient,
 	}
 }
 
-// RegisterRoutes registers all API routes
+// apiV1 is the prefix of the versioned route tree
+const apiV1 = "/api/v1"
+
+// RegisterRoutes registers all API routes under /api/v1. Legacy routes keep
+// their unversioned /api paths as deprecated aliases.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/api/card-event-map", h.handleCardEventMap)
-	mux.HandleFunc("/api/music-event-map", h.handleMusicEventMap)
-	mux.HandleFunc("/api/card-gacha-map", h.handleCardGachaMap)
-	mux.HandleFunc("/api/event-virtuallive-map", h.handleEventVirtualLiveMap)
-	mux.HandleFunc("/api/virtuallive-event-map", h.handleVirtualLiveEventMap)
-	mux.HandleFunc("/api/gachas", h.handleGachaList)
-	mux.HandleFunc("/api/gachas/", h.handleGachaDetail)
-	mux.HandleFunc("/api/cards/", h.handleCardCostumes)
-	mux.HandleFunc("/api/bilibili/dynamic/", h.handleBilibiliDynamic)
-	mux.HandleFunc("/api/bilibili/image", h.handleBilibiliImage)
+	for _, route := range h.routes() {
+		mux.HandleFunc(route.Method+" "+route.Path, route.Handler)
+		if route.Legacy {
+			mux.Handle(route.Method+" "+legacyPath(route.Path), deprecated(route.Handler))
+		}
+	}
+	mux.Handle("/api/", notFound(mux))
+
+	// Region-prefixed aliases: /api/{region}/... and /api/v1/{region}/...
+	// Every known region is routed so that regions that are not loaded get
+	// the same region_not_loaded error as with ?region=
+	for _, region := range masterdata.AllRegions {
+		mux.Handle("/api/"+string(region)+"/", h.withRegionPrefix(region, "/api", mux))
+		mux.Handle(apiV1+"/"+string(region)+"/", h.withRegionPrefix(region, apiV1, mux))
+	}
 }
 
-func (h *Handler) handleCardEventMap(w http.ResponseWriter, r *http.Request) {
+// legacyPath strips the version from a /api/v1 path
+func legacyPath(path string) string {
+	return "/api" + strings.TrimPrefix(path, apiV1)
+}
+
+// deprecated marks responses of an unversioned alias with a Deprecation
+// header and a link to its /api/v1 successor
+func deprecated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiV1+strings.TrimPrefix(r.URL.Path, "/api")+">; rel=\"successor-version\"")
+		next(w, r)
+	})
+}
+
+// probeMethods are tried on unmatched requests to tell a 405 from a 404
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// notFound answers API requests no route matched. When the path is routed
+// for other methods it writes a 405 with an Allow header, otherwise a 404.
+func notFound(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range probeMethods {
+			if method == r.Method {
+				continue
+			}
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && pattern != "/api/" {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			writeError(w, errNotFound("Resource"))
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		err := newAPIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method "+r.Method+" not allowed")
+		err.Details = map[string][]string{"allow": allowed}
+		writeError(w, err)
+	})
+}
+
+func (h *Handler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storeFor(w, r)
+	if !ok {
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.store.GetCardEventMap())
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(store.GetVersionInfo())
 }
 
-func (h *Handler) handleMusicEventMap(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) handleChangelog(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storeFor(w, r)
+	if !ok {
+		return
+	}
+	if notModified(w, r, store.Snapshot()) {
+		return
+	}
+	info := store.GetVersionInfo()
+	resp := models.ChangelogResponse{
+		Region:  info.Region,
+		Version: info.Version,
+		Entries: store.GetChangelog(r.URL.Query().Get("since")),
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.store.GetMusicEventMap())
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) handleCardEventMap(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	writeEncoded(w, r, snap, masterdata.EncodedCardEventMap, snap.CardEventMap)
+}
+
+func (h *Handler) handleMusicEventMap(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	writeEncoded(w, r, snap, masterdata.EncodedMusicEventMap, snap.MusicEventMap)
 }
 
 func (h *Handler) handleCardGachaMap(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.store.GetCardGachaMap())
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	writeEncoded(w, r, snap, masterdata.EncodedCardGachaMap, snap.CardGachaMap)
 }
 
 func (h *Handler) handleEventVirtualLiveMap(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.store.GetEventVirtualLiveMap())
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	writeEncoded(w, r, snap, masterdata.EncodedEventVirtualLiveMap, snap.EventVirtualLiveMap)
 }
 
 func (h *Handler) handleVirtualLiveEventMap(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.store.GetVirtualLiveEventMap())
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	writeEncoded(w, r, snap, masterdata.EncodedVirtualLiveEventMap, snap.VirtualLiveEventMap)
 }
 
-func (h *Handler) handleGachaList(w http.ResponseWriter, r *http.Request) {
-	// Parse Params
+// gachaFilter holds the parsed /api/gachas filter parameters
+type gachaFilter struct {
+	search    string
+	searchIDs map[int]bool
+	types     map[string]bool
+	startAt   int64
+	endAt     int64
+	hasStart  bool
+	hasEnd    bool
+	active    *bool
+	upcoming  *bool
+	now       int64
+
+	// Pickup filters, matched by a single pickup card
+	characters map[int]bool
+	units      map[string]bool
+	rarities   map[string]bool
+}
+
+func parseGachaFilter(r *http.Request) (*gachaFilter, error) {
 	query := r.URL.Query()
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page < 1 {
-		page = 1
+	f := &gachaFilter{
+		search: query.Get("search"),
+		types:  toSet(parseList(query, "gachaType")),
+		units:  toSet(parseList(query, "unit")),
+		now:    time.Now().UnixMilli(),
+	}
+
+	characters, err := parseIntList(query, "character")
+	if err != nil {
+		return nil, err
+	}
+	f.characters = toSet(characters)
+
+	var rarities []string
+	for _, v := range parseList(query, "rarity") {
+		rarities = append(rarities, normalizeRarity(v))
+	}
+	f.rarities = toSet(rarities)
+
+	if f.startAt, f.hasStart, err = parseInt64(query, "startAt"); err != nil {
+		return nil, err
+	}
+	if f.endAt, f.hasEnd, err = parseInt64(query, "endAt"); err != nil {
+		return nil, err
+	}
+	if f.active, err = parseOptionalBool(query, "active"); err != nil {
+		return nil, err
+	}
+	if f.upcoming, err = parseOptionalBool(query, "upcoming"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// dependsOnClock reports whether the result changes over time for the same
+// master data, so snapshot validators cannot be used
+func (f *gachaFilter) dependsOnClock() bool {
+	return f.active != nil || f.upcoming != nil
+}
+
+// match keeps gachas of the requested types whose startAt..endAt period
+// overlaps the startAt..endAt window, and with a pickup card matching every
+// pickup filter
+func (f *gachaFilter) match(snap *masterdata.Snapshot, g models.Gacha) bool {
+	if f.searchIDs != nil && !f.searchIDs[g.ID] {
+		return false
+	}
+	if f.types != nil && !f.types[g.GachaType] {
+		return false
+	}
+	if f.hasStart && g.EndAt < f.startAt {
+		return false
 	}
-	limit, _ := strconv.Atoi(query.Get("limit"))
-	if limit < 1 {
-		limit = 24
+	if f.hasEnd && g.StartAt > f.endAt {
+		return false
 	}
-	search := strings.ToLower(query.Get("search"))
+	if f.active != nil && (g.StartAt <= f.now && f.now <= g.EndAt) != *f.active {
+		return false
+	}
+	if f.upcoming != nil && (g.StartAt > f.now) != *f.upcoming {
+		return false
+	}
+	if f.characters == nil && f.units == nil && f.rarities == nil {
+		return true
+	}
+	for _, id := range snap.GachaPickups[g.ID] {
+		if card, ok := snap.CardByID[id]; ok && f.matchPickup(snap, card) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *gachaFilter) matchPickup(snap *masterdata.Snapshot, c models.Card) bool {
+	if f.characters != nil && !f.characters[c.CharacterID] {
+		return false
+	}
+	if f.rarities != nil && !f.rarities[c.CardRarityType] {
+		return false
+	}
+	if f.units != nil {
+		for _, unit := range snap.CardUnits(c) {
+			if f.units[unit] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (h *Handler) handleGachaList(w http.ResponseWriter, r *http.Request) {
+	// Parse Params
+	query := r.URL.Query()
+	page, limit := parsePaging(query)
 	sortBy := query.Get("sortBy")
 	sortOrder := query.Get("sortOrder")
+	filter, err := parseGachaFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	proj, err := parseProjection(query, models.GachaListItem{}, gachaRelations...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	gachaList := h.store.GetGachaList()
-	gachaPickups := h.store.GetGachaPickups()
+	var snap *masterdata.Snapshot
+	if filter.dependsOnClock() {
+		store, ok := h.storeFor(w, r)
+		if !ok {
+			return
+		}
+		snap = store.Snapshot()
+	} else {
+		var ok bool
+		if snap, ok = h.snapshotFor(w, r); !ok {
+			return
+		}
+	}
+	gachaPickups := snap.GachaPickups
 
 	// Filter
 	var filtered []models.Gacha
-	if search == "" {
-		filtered = make([]models.Gacha, len(gachaList))
-		copy(filtered, gachaList)
-	} else {
-		searchId, searchIdErr := strconv.Atoi(search)
-		for _, g := range gachaList {
-			if (searchIdErr == nil && g.ID == searchId) || strings.Contains(strings.ToLower(g.Name), search) {
-				filtered = append(filtered, g)
-			}
+	filter.searchIDs = snap.Text.MatchIDs(masterdata.DocGacha, filter.search)
+	for _, g := range masterdata.Gachas.Rows(snap) {
+		if filter.match(snap, g) {
+			filtered = append(filtered, g)
 		}
 	}
 
@@ -113,14 +343,7 @@ func (h *Handler) handleGachaList(w http.ResponseWriter, r *http.Request) {
 
 	// Paginate
 	total := len(filtered)
-	start := (page - 1) * limit
-	if start > total {
-		start = total
-	}
-	end := start + limit
-	if end > total {
-		end = total
-	}
+	start, end := pageBounds(total, page, limit)
 	paged := filtered[start:end]
 
 	// Map to Response
@@ -139,6 +362,12 @@ func (h *Handler) handleGachaList(w http.ResponseWriter, r *http.Request) {
 			EndAt:           g.EndAt,
 			PickupCardIds:   pickups,
 		}
+		if proj.has("pickupCards") {
+			resultItems[i].PickupCards = pickupCards(snap, pickups)
+		}
+		if proj.has("rarityRates") {
+			resultItems[i].RarityRates = g.GachaCardRarityRates
+		}
 	}
 
 	resp := models.GachaListResponse{
@@ -148,122 +377,95 @@ func (h *Handler) handleGachaList(w http.ResponseWriter, r *http.Request) {
 		Gachas: resultItems,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	proj.write(w, resp, "gachas")
 }
 
 func (h *Handler) handleGachaDetail(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 4 {
-		http.NotFound(w, r)
+	id, ok := pathInt(w, r, "id")
+	if !ok {
 		return
 	}
-	idStr := parts[3]
-	id, err := strconv.Atoi(idStr)
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+	proj, err := parseProjection(r.URL.Query(), models.GachaDetailResponse{}, gachaRelations...)
 	if err != nil {
-		http.NotFound(w, r)
+		writeError(w, err)
 		return
 	}
 
-	gachaList := h.store.GetGachaList()
-	gachaPickups := h.store.GetGachaPickups()
-
-	var found *models.Gacha
-	for i := range gachaList {
-		if gachaList[i].ID == id {
-			found = &gachaList[i]
-			break
-		}
+	gacha, found := snap.GachaByID[id]
+	if !found {
+		writeError(w, errNotFound("Gacha"))
+		return
 	}
 
-	if found == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Gacha not found"})
-		return
+	resp := buildGachaDetail(snap, gacha)
+	if proj.has("pickupCards") {
+		resp.PickupCards = pickupCards(snap, resp.PickupCardIds)
+	}
+	if proj.has("rarityRates") {
+		resp.RarityRates = gacha.GachaCardRarityRates
 	}
 
-	pickups := gachaPickups[found.ID]
+	proj.write(w, resp, "")
+}
+
+// buildGachaDetail joins a gacha with its pickup card IDs
+func buildGachaDetail(snap *masterdata.Snapshot, gacha models.Gacha) models.GachaDetailResponse {
+	pickups := snap.GachaPickups[gacha.ID]
 	if pickups == nil {
 		pickups = []int{}
 	}
-
-	resp := models.GachaDetailResponse{
-		Gacha:         *found,
+	return models.GachaDetailResponse{
+		Gacha:         gacha,
 		PickupCardIds: pickups,
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+// gachaRelations are the include= values of the gacha endpoints
+var gachaRelations = []string{"pickupCards", "rarityRates"}
+
+// pickupCards resolves pickup card IDs, skipping cards missing from the master data
+func pickupCards(snap *masterdata.Snapshot, ids []int) []models.Card {
+	cards := make([]models.Card, 0, len(ids))
+	for _, id := range ids {
+		if card, ok := snap.CardByID[id]; ok {
+			cards = append(cards, card)
+		}
+	}
+	return cards
 }
 
 func (h *Handler) handleCardCostumes(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 5 || parts[4] != "costumes" {
-		http.NotFound(w, r)
+	cardId, ok := pathInt(w, r, "id")
+	if !ok {
 		return
 	}
-	cardIdStr := parts[3]
-	cardId, err := strconv.Atoi(cardIdStr)
-	if err != nil {
-		http.NotFound(w, r)
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-
-	cardCostume3dMap := h.store.GetCardCostume3dMap()
-	costume3dGroupIdMap := h.store.GetCostume3dGroupIdMap()
-	costume3dGroupMap := h.store.GetCostume3dGroupMap()
-
-	costumeIds, ok := cardCostume3dMap[cardId]
-	if !ok || len(costumeIds) == 0 {
-		json.NewEncoder(w).Encode([]models.Costume3d{})
-		return
-	}
-
-	seenGroupIds := make(map[int]bool)
-	var result []models.Costume3d
-
-	for _, cid := range costumeIds {
-		groupId, exists := costume3dGroupIdMap[cid]
-		if exists {
-			if !seenGroupIds[groupId] {
-				seenGroupIds[groupId] = true
-				if groupItems, ok := costume3dGroupMap[groupId]; ok {
-					result = append(result, groupItems...)
-				}
-			}
-		}
-	}
-
-	if result == nil {
-		result = []models.Costume3d{}
-	}
-
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(snap.CardCostumes(cardId))
 }
 
 func (h *Handler) handleBilibiliDynamic(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid UID", http.StatusBadRequest)
-		return
-	}
-	uid := parts[4]
+	uid := r.PathValue("uid")
 	if uid == "" {
-		http.Error(w, "Empty UID", http.StatusBadRequest)
+		writeError(w, errMissingParam("uid"))
 		return
 	}
 
 	data, statusCode, err := h.bilibili.FetchDynamic(uid)
 	if err != nil {
-		http.Error(w, err.Error(), statusCode)
+		writeError(w, errUpstream(statusCode, err))
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	w.Write(data)
 }
@@ -271,13 +473,13 @@ func (h *Handler) handleBilibiliDynamic(w http.ResponseWriter, r *http.Request)
 func (h *Handler) handleBilibiliImage(w http.ResponseWriter, r *http.Request) {
 	imageUrl := r.URL.Query().Get("url")
 	if imageUrl == "" {
-		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		writeError(w, errMissingParam("url"))
 		return
 	}
 
 	data, contentType, statusCode, err := h.bilibili.FetchImage(imageUrl)
 	if err != nil {
-		http.Error(w, err.Error(), statusCode)
+		writeError(w, errUpstream(statusCode, err))
 		return
 	}
 
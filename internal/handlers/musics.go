@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+func (h *Handler) handleMusicList(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Params
+	query := r.URL.Query()
+	page, limit := parsePaging(query)
+	search := query.Get("search")
+	categories := toSet(parseList(query, "category"))
+	sortBy := query.Get("sortBy")
+	sortOrder := query.Get("sortOrder")
+
+	searchIDs := snap.Text.MatchIDs(masterdata.DocMusic, search)
+
+	// Filter
+	var filtered []models.Music
+	for _, m := range masterdata.Musics.Rows(snap) {
+		if searchIDs != nil && !searchIDs[m.ID] {
+			continue
+		}
+		if categories != nil {
+			found := false
+			for _, c := range m.Categories {
+				if categories[c] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		filtered = append(filtered, m)
+	}
+
+	// Sort
+	sort.SliceStable(filtered, func(i, j int) bool {
+		var less bool
+		if sortBy == "id" {
+			less = filtered[i].ID < filtered[j].ID
+		} else {
+			less = filtered[i].PublishedAt < filtered[j].PublishedAt
+		}
+		if sortOrder == "asc" {
+			return less
+		}
+		return !less
+	})
+
+	// Paginate
+	total := len(filtered)
+	start, end := pageBounds(total, page, limit)
+	paged := filtered[start:end]
+
+	// Map to Response
+	resultItems := make([]models.MusicListItem, len(paged))
+	for i, m := range paged {
+		diffs := snap.MusicDifficultiesByMusic[m.ID]
+		items := make([]models.MusicDifficultyItem, len(diffs))
+		for j, d := range diffs {
+			items[j] = models.MusicDifficultyItem{
+				MusicDifficulty: d.MusicDifficulty,
+				PlayLevel:       d.PlayLevel,
+				TotalNoteCount:  d.TotalNoteCount,
+			}
+		}
+		resultItems[i] = models.MusicListItem{Music: m, Difficulties: items}
+	}
+
+	resp := models.MusicListResponse{
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+		Musics: resultItems,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) handleMusicDetail(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	music, found := snap.MusicByID[id]
+	if !found {
+		writeError(w, errNotFound("Music"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildMusicDetail(snap, music))
+}
+
+// buildMusicDetail joins a music with its difficulties and events
+func buildMusicDetail(snap *masterdata.Snapshot, music models.Music) models.MusicDetailResponse {
+	resp := models.MusicDetailResponse{
+		Music:        music,
+		Difficulties: snap.MusicDifficultiesByMusic[music.ID],
+		Events:       snap.MusicEventMap[music.ID],
+	}
+	if resp.Difficulties == nil {
+		resp.Difficulties = []models.MusicDifficulty{}
+	}
+	if resp.Events == nil {
+		resp.Events = []models.EventInfo{}
+	}
+	return resp
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// schemaBuilder reflects Go types into OpenAPI schemas, collecting named
+// structs as components
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			b.components[t.Name()] = nil
+			b.components[t.Name()] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	// Interfaces and anything else accept any value
+	return map[string]any{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := make(map[string]bool)
+	b.addFields(t, props, required, false)
+
+	schema := map[string]any{"type": "object", "properties": props}
+	var names []string
+	for name := range props {
+		if required[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		schema["required"] = names
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of t to props, following encoding/json:
+// untagged embedded structs are flattened and outer fields win over them
+func (b *schemaBuilder) addFields(t reflect.Type, props map[string]any, required map[string]bool, embedded bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, props, required, true)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, exists := props[name]; exists && embedded {
+			continue
+		}
+		props[name] = b.schema(f.Type)
+		required[name] = !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer
+	}
+}
+
+// operationID derives an ID such as getApiCardsIdCostumes from a route
+func operationID(route Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	upper := true
+	for _, r := range route.Path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// openAPIDocument builds the OpenAPI 3 document describing h.routes()
+func (h *Handler) openAPIDocument() map[string]any {
+	b := &schemaBuilder{components: make(map[string]any)}
+	errorSchema := b.schema(reflect.TypeOf(ErrorResponse{}))
+
+	var regions []string
+	for _, region := range h.stores.Regions() {
+		regions = append(regions, string(region))
+	}
+
+	paths := make(map[string]any)
+	for _, route := range h.routes() {
+		var parameters []any
+		for _, p := range route.Params {
+			parameters = append(parameters, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.Required,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+		if route.Regional {
+			parameters = append(parameters, map[string]any{
+				"name":        "region",
+				"in":          "query",
+				"description": "Server region, also accepted as an /api/{region}/ path prefix",
+				"schema":      map[string]any{"type": "string", "enum": regions},
+			})
+		}
+
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		responseSchema := map[string]any{}
+		if route.Response != nil {
+			responseSchema = b.schema(reflect.TypeOf(route.Response))
+		} else if !strings.HasSuffix(contentType, "json") {
+			responseSchema = map[string]any{"type": "string", "format": "binary"}
+		}
+
+		operation := map[string]any{
+			"operationId": operationID(route),
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{contentType: map[string]any{"schema": responseSchema}},
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+				},
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": b.schema(reflect.TypeOf(route.Request)),
+				}},
+			}
+		}
+
+		item, _ := paths[route.Path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Snowy Viewer API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": b.components},
+	}
+}
+
+func (h *Handler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.openAPIOnce.Do(func() {
+		h.openAPI, _ = json.Marshal(h.openAPIDocument())
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.openAPI)
+}
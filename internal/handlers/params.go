@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// pathInt reads an integer path parameter. It writes a 400 on failure.
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.PathValue(name)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		writeError(w, errInvalidParam(name, v))
+		return 0, false
+	}
+	return n, true
+}
+
+// maxPageSize caps the limit of paged lists
+const maxPageSize = 500
+
+// parsePaging reads page and limit, defaulting to page 1 of 24 items and
+// capping limit at maxPageSize
+func parsePaging(query url.Values) (int, int) {
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 24
+	}
+	return page, min(limit, maxPageSize)
+}
+
+// pageBounds returns the slice bounds of a page within total items
+func pageBounds(total, page, limit int) (int, int) {
+	// Pages past the end are empty; checked before multiplying so that large
+	// pages cannot overflow
+	if page-1 > total/limit {
+		return total, total
+	}
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// parseList splits a comma separated parameter, dropping empty items
+func parseList(query url.Values, key string) []string {
+	var items []string
+	for _, item := range strings.Split(query.Get(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseIntList parses a comma separated list of integers
+func parseIntList(query url.Values, key string) ([]int, error) {
+	var ids []int
+	for _, item := range parseList(query, key) {
+		id, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, errInvalidParam(key, item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseInt64 parses an optional integer parameter such as a millisecond timestamp
+func parseInt64(query url.Values, key string) (int64, bool, error) {
+	v := query.Get(key)
+	if v == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, false, errInvalidParam(key, v)
+	}
+	return n, true, nil
+}
+
+// parseIntInRange parses an optional integer parameter within [min, max]
+func parseIntInRange(query url.Values, key string, def, min, max int) (int, error) {
+	v := query.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, errInvalidParam(key, v)
+	}
+	return n, nil
+}
+
+// parseOptionalBool parses an optional true/false parameter
+func parseOptionalBool(query url.Values, key string) (*bool, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, errInvalidParam(key, v)
+	}
+	return &b, nil
+}
+
+func toSet[T comparable](items []T) map[T]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[T]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"snowy_viewer/internal/masterdata"
+)
+
+type regionContextKey struct{}
+
+// withRegionPrefix serves {base}/{region}/... by rewriting it to {base}/...
+// and pinning the region on the request context. Versioned paths are only
+// served as /api/v1/{region}/..., not /api/{region}/v1/...
+func (h *Handler) withRegionPrefix(region masterdata.Region, base string, next http.Handler) http.Handler {
+	prefix := base + "/" + string(region)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, prefix)
+		if base != apiV1 && (base+rest == apiV1 || strings.HasPrefix(base+rest, apiV1+"/")) {
+			writeError(w, errNotFound("Resource"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), regionContextKey{}, region)
+		r2 := r.WithContext(ctx)
+		u := new(url.URL)
+		*u = *r.URL
+		u.Path = base + rest
+		u.RawPath = ""
+		r2.URL = u
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// storeFor resolves the master data store for a request from the path prefix
+// or the region query parameter. It writes an error response on failure.
+func (h *Handler) storeFor(w http.ResponseWriter, r *http.Request) (*masterdata.Store, bool) {
+	region, ok := r.Context().Value(regionContextKey{}).(masterdata.Region)
+	if !ok {
+		name := r.URL.Query().Get("region")
+		if name == "" {
+			return h.stores.Default(), true
+		}
+		region, ok = masterdata.ParseRegion(name)
+		if !ok {
+			writeError(w, newAPIError(http.StatusBadRequest, CodeUnknownRegion, "Unknown region: "+name))
+			return nil, false
+		}
+	}
+
+	store, ok := h.stores.Get(region)
+	if !ok {
+		writeError(w, newAPIError(http.StatusBadRequest, CodeRegionNotLoaded, "Region not loaded: "+string(region)))
+		return nil, false
+	}
+	return store, true
+}
+
+// snapshotFor resolves the store like storeFor and returns its current
+// snapshot. It reports false when an error or a 304 Not Modified was written.
+func (h *Handler) snapshotFor(w http.ResponseWriter, r *http.Request) (*masterdata.Snapshot, bool) {
+	store, ok := h.storeFor(w, r)
+	if !ok {
+		return nil, false
+	}
+	snap := store.Snapshot()
+	if notModified(w, r, snap) {
+		return nil, false
+	}
+	return snap, true
+}
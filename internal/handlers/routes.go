@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"snowy_viewer/internal/graphql"
+	"snowy_viewer/internal/models"
+)
+
+// Param describes a path or query parameter of a route
+type Param struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // "string", "integer" or "boolean"
+	Description string
+	Required    bool
+}
+
+// Route describes one API operation and the handler serving it
+type Route struct {
+	Handler http.HandlerFunc
+	// Legacy routes are also served without the version prefix, as deprecated aliases
+	Legacy bool
+
+	Method  string
+	Path    string // ServeMux and OpenAPI path, e.g. /api/v1/cards/{id}
+	Tag     string
+	Summary string
+	Params  []Param
+	// Regional routes accept ?region= and the /api/{region}/ prefix
+	Regional bool
+	// Request is a value of the JSON request body model, if any
+	Request any
+	// Response is a value of the 200 response model; nil documents an untyped body
+	Response any
+	// ContentType of the 200 response, default application/json
+	ContentType string
+}
+
+func queryParam(name, typ, description string) Param {
+	return Param{Name: name, In: "query", Type: typ, Description: description}
+}
+
+func pathParam(name, typ, description string) Param {
+	return Param{Name: name, In: "path", Type: typ, Description: description, Required: true}
+}
+
+var (
+	idParam      = pathParam("id", "integer", "Resource ID")
+	searchParam  = queryParam("search", "string", "Matches names by text or the exact ID")
+	pagingParams = []Param{
+		queryParam("page", "integer", "Page number, default 1"),
+		queryParam("limit", "integer", "Items per page, default 24, at most 500"),
+	}
+)
+
+// sortParams documents sortBy with its allowed keys; sortOrder defaults to desc
+func sortParams(keys string) []Param {
+	return []Param{
+		queryParam("sortBy", "string", "Sort key: "+keys),
+		queryParam("sortOrder", "string", "asc or desc"),
+	}
+}
+
+// projectionParams documents fields= and include= for the given relations
+func projectionParams(relations []string) []Param {
+	return []Param{
+		queryParam("fields", "string", "Comma separated fields to return"),
+		queryParam("include", "string", "Comma separated relations to embed: "+strings.Join(relations, ", ")),
+	}
+}
+
+func params(groups ...[]Param) []Param {
+	var all []Param
+	for _, g := range groups {
+		all = append(all, g...)
+	}
+	return all
+}
+
+// routes lists every API operation in registration order
+func (h *Handler) routes() []Route {
+	return []Route{
+		{
+			Handler: h.handleVersion, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/version", Tag: "master",
+			Summary:  "Master data version, sources and table counts",
+			Regional: true, Response: models.MasterVersionInfo{},
+		},
+		{
+			Handler: h.handleChangelog, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/changelog", Tag: "master",
+			Summary:  "Entities added by recent master data updates",
+			Params:   []Param{queryParam("since", "string", "Only entries after this master version")},
+			Regional: true, Response: models.ChangelogResponse{},
+		},
+		{
+			Handler: h.handleCardEventMap, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/card-event-map", Tag: "maps",
+			Summary:  "Event of each card, keyed by card ID",
+			Regional: true, Response: map[int]models.EventInfo{},
+		},
+		{
+			Handler: h.handleMusicEventMap, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/music-event-map", Tag: "maps",
+			Summary:  "Events of each music, keyed by music ID",
+			Regional: true, Response: map[int][]models.EventInfo{},
+		},
+		{
+			Handler: h.handleCardGachaMap, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/card-gacha-map", Tag: "maps",
+			Summary:  "Gachas picking up each card, keyed by card ID",
+			Regional: true, Response: map[int][]models.GachaInfo{},
+		},
+		{
+			Handler: h.handleEventVirtualLiveMap, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/event-virtuallive-map", Tag: "maps",
+			Summary:  "Virtual live of each event, keyed by event ID",
+			Regional: true, Response: map[int]models.VirtualLiveInfo{},
+		},
+		{
+			Handler: h.handleVirtualLiveEventMap, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/virtuallive-event-map", Tag: "maps",
+			Summary:  "Event of each virtual live, keyed by virtual live ID",
+			Regional: true, Response: map[int]models.EventInfo{},
+		},
+		{
+			Handler: h.handleSearch, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/search", Tag: "search",
+			Summary: "Search events, gachas, cards, musics, virtual lives and costumes",
+			Params: []Param{
+				{Name: "q", In: "query", Type: "string", Description: "Query text or ID", Required: true},
+				queryParam("types", "string", "Comma separated result types"),
+				queryParam("limit", "integer", "Results per type, default 10, at most 100"),
+			},
+			Regional: true, Response: models.SearchResponse{},
+		},
+		{
+			Handler: h.handleEventList, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/events", Tag: "events",
+			Summary: "List events",
+			Params: params([]Param{
+				searchParam,
+				queryParam("type", "string", "Comma separated event types"),
+				queryParam("unit", "string", "Comma separated units"),
+				queryParam("from", "integer", "Events running at or after this time (ms)"),
+				queryParam("to", "integer", "Events running at or before this time (ms)"),
+			}, sortParams("startAt, id"), pagingParams, projectionParams(eventRelations)),
+			Regional: true, Response: models.EventListResponse{},
+		},
+		{
+			Handler: h.handleEventDetail, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/events/{id}", Tag: "events",
+			Summary:  "Event, with its bonus cards, musics and virtual live on include=",
+			Params:   params([]Param{idParam}, projectionParams(eventRelations)),
+			Regional: true, Response: models.EventDetailResponse{},
+		},
+		{
+			Handler: h.handleMusicList, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/musics", Tag: "musics",
+			Summary: "List musics with their difficulties",
+			Params: params([]Param{
+				queryParam("search", "string", "Matches title, reading, composer, lyricist or the exact ID"),
+				queryParam("category", "string", "Comma separated categories"),
+			}, sortParams("publishedAt, id"), pagingParams),
+			Regional: true, Response: models.MusicListResponse{},
+		},
+		{
+			Handler: h.handleMusicDetail, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/musics/{id}", Tag: "musics",
+			Summary:  "Music with its difficulties and events",
+			Params:   []Param{idParam},
+			Regional: true, Response: models.MusicDetailResponse{},
+		},
+		{
+			Handler: h.handleGachaList, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/gachas", Tag: "gachas",
+			Summary: "List gachas",
+			Params: params([]Param{
+				searchParam,
+				queryParam("gachaType", "string", "Comma separated gacha types"),
+				queryParam("startAt", "integer", "Running at or after this time (ms)"),
+				queryParam("endAt", "integer", "Running at or before this time (ms)"),
+				queryParam("active", "boolean", "Only running or only not running gachas"),
+				queryParam("upcoming", "boolean", "Only or no gachas that have not started"),
+				queryParam("character", "string", "Comma separated character IDs of a pickup card"),
+				queryParam("unit", "string", "Comma separated units of a pickup card"),
+				queryParam("rarity", "string", "Comma separated rarities of a pickup card"),
+			}, sortParams("startAt, id"), pagingParams, projectionParams(gachaRelations)),
+			Regional: true, Response: models.GachaListResponse{},
+		},
+		{
+			Handler: h.handleGachaDetail, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/gachas/{id}", Tag: "gachas",
+			Summary:  "Gacha with its pickup cards",
+			Params:   params([]Param{idParam}, projectionParams(gachaRelations)),
+			Regional: true, Response: models.GachaDetailResponse{},
+		},
+		{
+			Handler: h.handleGachaRates,
+			Method:  http.MethodGet, Path: "/api/v1/gachas/{id}/rates", Tag: "gachas",
+			Summary:  "Per-card pull odds, pickup versus off-rate, and guaranteed 10-pull slot odds",
+			Params:   []Param{idParam},
+			Regional: true, Response: models.GachaRatesResponse{},
+		},
+		{
+			Handler: h.handleGachaSimulate,
+			Method:  http.MethodGet, Path: "/api/v1/gachas/{id}/simulate", Tag: "gachas",
+			Summary: "Seeded pull simulation, or the Monte Carlo distribution of pulls needed for a card",
+			Params: []Param{
+				idParam,
+				queryParam("mode", "string", "single (default) or montecarlo"),
+				queryParam("seed", "integer", "RNG seed; a random one is returned when omitted, and the response is then sent without validators"),
+				queryParam("pulls", "integer", "Pulls to simulate in single mode, default 10, at most 3000"),
+				queryParam("trials", "integer", "Trials in montecarlo mode, default 10000, at most 50000, reduced to keep the total pulls within 5000000"),
+				queryParam("target", "integer", "Card to pull or spark, default the top rarity pickup"),
+				queryParam("behaviorId", "integer", "Spin behavior, default the guaranteed 10-pull"),
+			},
+			Regional: true, Response: models.GachaSimulationResponse{},
+		},
+		{
+			Handler: h.handleCardList, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/cards", Tag: "cards",
+			Summary: "List cards",
+			Params: params([]Param{
+				searchParam,
+				queryParam("character", "string", "Comma separated character IDs"),
+				queryParam("unit", "string", "Comma separated units"),
+				queryParam("rarity", "string", "Comma separated rarities, e.g. 4 or rarity_birthday"),
+				queryParam("attr", "string", "Comma separated attributes"),
+				queryParam("releaseFrom", "integer", "Released at or after this time (ms)"),
+				queryParam("releaseTo", "integer", "Released at or before this time (ms)"),
+				queryParam("limited", "boolean", "Only limited or only permanent cards"),
+				queryParam("event", "boolean", "Only event or only non-event cards"),
+			}, sortParams("id, releaseAt, rarity"), pagingParams, projectionParams(cardRelations)),
+			Regional: true, Response: models.CardListResponse{},
+		},
+		{
+			Handler: h.handleCardCostumesBatch,
+			Method:  http.MethodGet, Path: "/api/v1/cards/costumes", Tag: "cards",
+			Summary:  "Costume parts of many cards, keyed by card ID",
+			Params:   []Param{{Name: "ids", In: "query", Type: "string", Description: "Comma separated card IDs", Required: true}},
+			Regional: true, Response: map[int]BatchItem{},
+		},
+		{
+			Handler: h.handleCardDetail, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/cards/{id}", Tag: "cards",
+			Summary:  "Card, with its character, skill, event, gachas and costumes on include=",
+			Params:   params([]Param{idParam}, projectionParams(cardRelations)),
+			Regional: true, Response: models.CardDetailResponse{},
+		},
+		{
+			Handler: h.handleCardCostumes, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/cards/{id}/costumes", Tag: "cards",
+			Summary:  "Costume parts unlocked by a card",
+			Params:   []Param{idParam},
+			Regional: true, Response: []models.Costume3d{},
+		},
+		{
+			Handler: h.handleCardGachaHistory,
+			Method:  http.MethodGet, Path: "/api/v1/cards/{id}/gacha-history", Tag: "cards",
+			Summary:  "Gachas featuring a card, split into debut and reruns with the intervals between them",
+			Params:   []Param{idParam},
+			Regional: true, Response: models.CardGachaHistoryResponse{},
+		},
+		{
+			Handler: h.handleTimeline,
+			Method:  http.MethodGet, Path: "/api/v1/timeline", Tag: "timeline",
+			Summary: "Events, gachas and virtual lives overlapping a time window, with their phases",
+			Params: []Param{
+				{Name: "from", In: "query", Type: "integer", Description: "Window start (ms)", Required: true},
+				{Name: "to", In: "query", Type: "integer", Description: "Window end (ms)", Required: true},
+				queryParam("types", "string", "Comma separated entry types: event, gacha, virtualLive"),
+			},
+			Regional: true, Response: models.TimelineResponse{},
+		},
+		{
+			Handler: h.handleBatch,
+			Method:  http.MethodPost, Path: "/api/v1/batch", Tag: "batch",
+			Summary:  "Look up many cards, card costumes, events, gachas and musics from one snapshot",
+			Regional: true, Request: BatchRequest{}, Response: BatchResponse{},
+		},
+		{
+			Handler: h.handleGraphQL,
+			Method:  http.MethodGet, Path: "/api/v1/graphql", Tag: "graphql",
+			Summary: "Run a GraphQL query against master data",
+			Params: []Param{
+				{Name: "query", In: "query", Type: "string", Description: "GraphQL query document", Required: true},
+				queryParam("variables", "string", "JSON object of query variables"),
+				queryParam("operationName", "string", "Operation to run when the document has several"),
+			},
+			Regional: true, Response: graphql.Response{},
+		},
+		{
+			Handler: h.handleGraphQL,
+			Method:  http.MethodPost, Path: "/api/v1/graphql", Tag: "graphql",
+			Summary:  "Run a GraphQL query against master data",
+			Regional: true, Request: graphql.Request{}, Response: graphql.Response{},
+		},
+		{
+			Handler: h.handleBilibiliDynamic, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/bilibili/dynamic/{uid}", Tag: "bilibili",
+			Summary: "Bilibili space dynamics of a user, proxied as is",
+			Params:  []Param{pathParam("uid", "string", "Bilibili user ID")},
+		},
+		{
+			Handler: h.handleBilibiliImage, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/bilibili/image", Tag: "bilibili",
+			Summary:     "Bilibili image proxy",
+			Params:      []Param{{Name: "url", In: "query", Type: "string", Description: "Image URL", Required: true}},
+			ContentType: "image/*",
+		},
+		{
+			Handler: h.handleOpenAPI, Legacy: true,
+			Method: http.MethodGet, Path: "/api/v1/openapi.json", Tag: "meta",
+			Summary: "This OpenAPI document",
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// Search result types, in the order groups are listed when scores tie
+var searchTypes = []string{
+	masterdata.DocEvent,
+	masterdata.DocGacha,
+	masterdata.DocCard,
+	masterdata.DocMusic,
+	masterdata.DocVirtualLive,
+	masterdata.DocCostume,
+}
+
+// maxSearchLimit caps the results returned per type
+const maxSearchLimit = 100
+
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Params
+	query := r.URL.Query()
+	q := query.Get("q")
+	if strings.TrimSpace(q) == "" {
+		writeError(w, errMissingParam("q"))
+		return
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 10
+	}
+	limit = min(limit, maxSearchLimit)
+	types := toSet(parseList(query, "types"))
+
+	// Search
+	results := make(map[string][]models.SearchResult)
+	for _, hit := range snap.Text.Search(q, types) {
+		results[hit.Type] = append(results[hit.Type], hit)
+	}
+
+	// Rank and group
+	resp := models.SearchResponse{Query: q, Groups: []models.SearchGroup{}}
+	for _, typ := range searchTypes {
+		items := results[typ]
+		if len(items) == 0 {
+			continue
+		}
+		// Best matches first, newer entries first among equals
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Score != items[j].Score {
+				return items[i].Score > items[j].Score
+			}
+			return items[i].ID > items[j].ID
+		})
+		resp.Total += len(items)
+		group := models.SearchGroup{Type: typ, Total: len(items), Results: items}
+		if len(group.Results) > limit {
+			group.Results = group.Results[:limit]
+		}
+		resp.Groups = append(resp.Groups, group)
+	}
+	sort.SliceStable(resp.Groups, func(i, j int) bool {
+		return resp.Groups[i].Results[0].Score > resp.Groups[j].Results[0].Score
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"snowy_viewer/internal/masterdata"
+	"snowy_viewer/internal/models"
+)
+
+// timelineTypeOrder lists the entry types, ordering entries starting at the same time
+var timelineTypeOrder = map[string]int{
+	models.TimelineEvent:       0,
+	models.TimelineGacha:       1,
+	models.TimelineVirtualLive: 2,
+}
+
+// eventTimelineEntry splits an event into its running, aggregate and closed
+// phases, skipping phases whose boundaries are missing
+func eventTimelineEntry(e models.Event) models.TimelineEntry {
+	entry := models.TimelineEntry{
+		Type:            models.TimelineEvent,
+		ID:              e.ID,
+		Name:            e.Name,
+		AssetbundleName: e.AssetbundleName,
+		Subtype:         e.EventType,
+		StartAt:         e.StartAt,
+		EndAt:           e.ClosedAt,
+		Phases:          []models.TimelinePhase{},
+	}
+	addPhase := func(name string, start, end int64) {
+		if start > 0 && end > start {
+			entry.Phases = append(entry.Phases, models.TimelinePhase{Name: name, StartAt: start, EndAt: end})
+		}
+	}
+	addPhase("running", e.StartAt, e.AggregateAt)
+	addPhase("aggregate", e.AggregateAt, e.ClosedAt)
+	addPhase("closed", e.ClosedAt, e.DistributionEndAt)
+	return entry
+}
+
+func gachaTimelineEntry(g models.Gacha) models.TimelineEntry {
+	return models.TimelineEntry{
+		Type:            models.TimelineGacha,
+		ID:              g.ID,
+		Name:            g.Name,
+		AssetbundleName: g.AssetbundleName,
+		Subtype:         g.GachaType,
+		StartAt:         g.StartAt,
+		EndAt:           g.EndAt,
+		Phases:          []models.TimelinePhase{{Name: "running", StartAt: g.StartAt, EndAt: g.EndAt}},
+	}
+}
+
+// virtualLiveTimelineEntry lists every scheduled performance as a phase
+func virtualLiveTimelineEntry(vl models.VirtualLive) models.TimelineEntry {
+	entry := models.TimelineEntry{
+		Type:            models.TimelineVirtualLive,
+		ID:              vl.ID,
+		Name:            vl.Name,
+		AssetbundleName: vl.AssetbundleName,
+		Subtype:         vl.VirtualLiveType,
+		StartAt:         vl.StartAt,
+		EndAt:           vl.EndAt,
+		Phases:          []models.TimelinePhase{},
+	}
+	for _, s := range vl.VirtualLiveSchedules {
+		seq := s.Seq
+		entry.Phases = append(entry.Phases, models.TimelinePhase{
+			Name:    "performance",
+			Seq:     &seq,
+			StartAt: s.StartAt,
+			EndAt:   s.EndAt,
+		})
+	}
+	sort.SliceStable(entry.Phases, func(i, j int) bool { return entry.Phases[i].StartAt < entry.Phases[j].StartAt })
+	return entry
+}
+
+// buildTimeline merges the entries of the requested types overlapping the
+// from..to window, ordered by start time
+func buildTimeline(snap *masterdata.Snapshot, from, to int64, types map[string]bool) []models.TimelineEntry {
+	entries := []models.TimelineEntry{}
+	add := func(entry models.TimelineEntry) {
+		if entry.StartAt <= to && entry.EndAt >= from {
+			entries = append(entries, entry)
+		}
+	}
+	if types == nil || types[models.TimelineEvent] {
+		for _, e := range masterdata.Events.Rows(snap) {
+			add(eventTimelineEntry(e))
+		}
+	}
+	if types == nil || types[models.TimelineGacha] {
+		for _, g := range masterdata.Gachas.Rows(snap) {
+			add(gachaTimelineEntry(g))
+		}
+	}
+	if types == nil || types[models.TimelineVirtualLive] {
+		for _, vl := range masterdata.VirtualLives.Rows(snap) {
+			add(virtualLiveTimelineEntry(vl))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.StartAt != b.StartAt {
+			return a.StartAt < b.StartAt
+		}
+		if a.Type != b.Type {
+			return timelineTypeOrder[a.Type] < timelineTypeOrder[b.Type]
+		}
+		return a.ID < b.ID
+	})
+	return entries
+}
+
+func (h *Handler) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	snap, ok := h.snapshotFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse Params
+	query := r.URL.Query()
+	from, hasFrom, err := parseInt64(query, "from")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, hasTo, err := parseInt64(query, "to")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !hasFrom {
+		writeError(w, errMissingParam("from"))
+		return
+	}
+	if !hasTo {
+		writeError(w, errMissingParam("to"))
+		return
+	}
+	if to < from {
+		writeError(w, errInvalidParam("to", strconv.FormatInt(to, 10)))
+		return
+	}
+	types := toSet(parseList(query, "types"))
+	for t := range types {
+		if _, ok := timelineTypeOrder[t]; !ok {
+			writeError(w, errInvalidParam("types", t))
+			return
+		}
+	}
+
+	entries := buildTimeline(snap, from, to, types)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TimelineResponse{
+		From:    from,
+		To:      to,
+		Total:   len(entries),
+		Entries: entries,
+	})
+}
@@ -0,0 +1,129 @@
+package masterdata
+
+import (
+	"sort"
+
+	"snowy_viewer/internal/models"
+)
+
+// changelogLimit bounds how many changelog entries a store keeps
+const changelogLimit = 50
+
+// diffSnapshots lists everything present in next but not in prev.
+// Version and time fields are left for the caller to fill in.
+func diffSnapshots(prev, next *Snapshot) models.ChangelogEntry {
+	entry := models.ChangelogEntry{
+		NewEvents:     []models.EventInfo{},
+		NewGachas:     []models.GachaInfo{},
+		NewEventCards: []models.EventCardChange{},
+		NewCostumes:   []models.Costume3d{},
+	}
+
+	for _, e := range Events.Rows(next) {
+		if _, ok := prev.EventByID[e.ID]; !ok {
+			entry.NewEvents = append(entry.NewEvents, eventInfo(e))
+		}
+	}
+	sort.Slice(entry.NewEvents, func(i, j int) bool { return entry.NewEvents[i].ID < entry.NewEvents[j].ID })
+
+	prevGachas := make(map[int]bool)
+	for _, g := range Gachas.Rows(prev) {
+		prevGachas[g.ID] = true
+	}
+	for _, g := range Gachas.Rows(next) {
+		if !prevGachas[g.ID] {
+			entry.NewGachas = append(entry.NewGachas, models.GachaInfo{
+				ID:              g.ID,
+				Name:            g.Name,
+				AssetbundleName: g.AssetbundleName,
+			})
+		}
+	}
+	sort.Slice(entry.NewGachas, func(i, j int) bool { return entry.NewGachas[i].ID < entry.NewGachas[j].ID })
+
+	prevEventCards := make(map[[2]int]bool)
+	for _, ec := range EventCards.Rows(prev) {
+		prevEventCards[[2]int{ec.CardID, ec.EventID}] = true
+	}
+	for _, ec := range EventCards.Rows(next) {
+		if prevEventCards[[2]int{ec.CardID, ec.EventID}] {
+			continue
+		}
+		change := models.EventCardChange{CardID: ec.CardID, Event: models.EventInfo{ID: ec.EventID}}
+		if e, ok := next.EventByID[ec.EventID]; ok {
+			change.Event = eventInfo(e)
+		}
+		entry.NewEventCards = append(entry.NewEventCards, change)
+	}
+	sort.Slice(entry.NewEventCards, func(i, j int) bool {
+		a, b := entry.NewEventCards[i], entry.NewEventCards[j]
+		if a.Event.ID != b.Event.ID {
+			return a.Event.ID < b.Event.ID
+		}
+		return a.CardID < b.CardID
+	})
+
+	for _, c := range Costume3ds.Rows(next) {
+		if _, ok := prev.Costume3dGroupIdMap[c.ID]; !ok {
+			entry.NewCostumes = append(entry.NewCostumes, c)
+		}
+	}
+	sort.Slice(entry.NewCostumes, func(i, j int) bool { return entry.NewCostumes[i].ID < entry.NewCostumes[j].ID })
+
+	return entry
+}
+
+func changelogEmpty(e models.ChangelogEntry) bool {
+	return len(e.NewEvents) == 0 && len(e.NewGachas) == 0 &&
+		len(e.NewEventCards) == 0 && len(e.NewCostumes) == 0
+}
+
+// recordChangelog diffs the new snapshot against the current one and appends
+// a non-empty result to the bounded history. Caller must hold s.mutex.
+func (s *Store) recordChangelog(prev, next *Snapshot) {
+	if prev.VersionInfo.LoadedAt == 0 {
+		// Initial load, nothing to compare against
+		return
+	}
+
+	entry := diffSnapshots(prev, next)
+	if changelogEmpty(entry) {
+		return
+	}
+	entry.FromVersion = prev.VersionInfo.Version
+	entry.Version = next.VersionInfo.Version
+	entry.Time = next.VersionInfo.LoadedAt
+
+	s.changelog = append(s.changelog, entry)
+	if len(s.changelog) > changelogLimit {
+		s.changelog = append([]models.ChangelogEntry(nil), s.changelog[len(s.changelog)-changelogLimit:]...)
+	}
+}
+
+// GetChangelog returns the entries recorded after the given version.
+// An empty or unknown version returns the whole retained history.
+func (s *Store) GetChangelog(since string) []models.ChangelogEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	start := 0
+	if since != "" {
+		for i := len(s.changelog) - 1; i >= 0; i-- {
+			if s.changelog[i].Version == since {
+				start = i + 1
+				break
+			}
+			if s.changelog[i].FromVersion == since {
+				start = i
+				break
+			}
+		}
+		if since == s.current.VersionInfo.Version {
+			start = len(s.changelog)
+		}
+	}
+
+	result := make([]models.ChangelogEntry, len(s.changelog)-start)
+	copy(result, s.changelog[start:])
+	return result
+}
@@ -0,0 +1,83 @@
+package masterdata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Names of the response bodies pre-encoded for every snapshot
+const (
+	EncodedCardEventMap        = "card-event-map"
+	EncodedMusicEventMap       = "music-event-map"
+	EncodedCardGachaMap        = "card-gacha-map"
+	EncodedEventVirtualLiveMap = "event-virtuallive-map"
+	EncodedVirtualLiveEventMap = "virtuallive-event-map"
+)
+
+// brotliLevel trades a little size for encoding time; the best level is an
+// order of magnitude slower on the larger maps
+const brotliLevel = 9
+
+// EncodedJSON is a JSON response body encoded once per snapshot, with its
+// gzip and brotli variants
+type EncodedJSON struct {
+	Raw    []byte
+	Gzip   []byte
+	Brotli []byte
+}
+
+func encodeJSON(v any) (*EncodedJSON, error) {
+	var raw bytes.Buffer
+	if err := json.NewEncoder(&raw).Encode(v); err != nil {
+		return nil, err
+	}
+	var gz bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	var br bytes.Buffer
+	bw := brotli.NewWriterLevel(&br, brotliLevel)
+	if _, err := bw.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return &EncodedJSON{Raw: raw.Bytes(), Gzip: gz.Bytes(), Brotli: br.Bytes()}, nil
+}
+
+// encodeResponses pre-encodes the map endpoint bodies of a snapshot
+func (snap *Snapshot) encodeResponses() error {
+	bodies := map[string]any{
+		EncodedCardEventMap:        snap.CardEventMap,
+		EncodedMusicEventMap:       snap.MusicEventMap,
+		EncodedCardGachaMap:        snap.CardGachaMap,
+		EncodedEventVirtualLiveMap: snap.EventVirtualLiveMap,
+		EncodedVirtualLiveEventMap: snap.VirtualLiveEventMap,
+	}
+	for name, v := range bodies {
+		encoded, err := encodeJSON(v)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+		snap.encoded[name] = encoded
+	}
+	return nil
+}
+
+// Encoded returns a pre-encoded response body by name
+func (snap *Snapshot) Encoded(name string) (*EncodedJSON, bool) {
+	encoded, ok := snap.encoded[name]
+	return encoded, ok
+}
@@ -1,331 +1,291 @@
 package masterdata
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"snowy_viewer/internal/models"
 )
 
-const (
-	EventsURL         = "https://sekaimaster.exmeaning.com/master/events.json"
-	EventCardsURL     = "https://sekaimaster.exmeaning.com/master/eventCards.json"
-	EventMusicsURL    = "https://sekaimaster.exmeaning.com/master/eventMusics.json"
-	VirtualLivesURL   = "https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-master/main/master/virtualLives.json"
-	GachasURL         = "https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-master/main/master/gachas.json"
-	CardCostume3dsURL = "https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-master/main/master/cardCostume3ds.json"
-	Costume3dsURL     = "https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-master/main/master/costume3ds.json"
-)
-
-// Store holds all master data in memory
+// Store holds the master data of one region in memory
 type Store struct {
-	mutex sync.RWMutex
-
-	// Card/Event/Music mappings
-	CardEventMap  map[int]models.EventInfo
-	MusicEventMap map[int][]models.EventInfo
-	CardGachaMap  map[int][]models.GachaInfo
+	mutex     sync.RWMutex
+	loadMutex sync.Mutex
 
-	// Event <-> VirtualLive mappings
-	EventVirtualLiveMap map[int]models.VirtualLiveInfo
-	VirtualLiveEventMap map[int]models.EventInfo
-
-	// Gacha data
-	GachaList    []models.Gacha
-	GachaPickups map[int][]int
-
-	// Costume mappings
-	CardCostume3dMap    map[int][]int
-	Costume3dGroupIdMap map[int]int
-	Costume3dGroupMap   map[int][]models.Costume3d
+	current   *Snapshot
+	changelog []models.ChangelogEntry
 
 	// Config
+	region        Region
 	localDataPath string
+	baseURLs      []string
 }
 
-// NewStore creates a new master data store
-func NewStore(localDataPath string) *Store {
+// NewStore creates a new master data store for a region
+func NewStore(region Region, localDataPath string, baseURLs []string) *Store {
 	return &Store{
-		CardEventMap:        make(map[int]models.EventInfo),
-		MusicEventMap:       make(map[int][]models.EventInfo),
-		CardGachaMap:        make(map[int][]models.GachaInfo),
-		EventVirtualLiveMap: make(map[int]models.VirtualLiveInfo),
-		VirtualLiveEventMap: make(map[int]models.EventInfo),
-		GachaPickups:        make(map[int][]int),
-		CardCostume3dMap:    make(map[int][]int),
-		Costume3dGroupIdMap: make(map[int]int),
-		Costume3dGroupMap:   make(map[int][]models.Costume3d),
-		localDataPath:       localDataPath,
+		current:       newSnapshot(),
+		region:        region,
+		localDataPath: localDataPath,
+		baseURLs:      baseURLs,
 	}
 }
 
-func fetchJSON(url string, target interface{}) error {
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Get(url)
+// Region returns the region this store serves
+func (s *Store) Region() Region {
+	return s.region
+}
+
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// fetchJSON downloads and decodes url into target, returning the response
+// ETag and the SHA-256 of the body
+func fetchJSON(ctx context.Context, url string, target interface{}) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return "", "", err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
+		return "", "", fmt.Errorf("bad status: %s", resp.Status)
 	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", "", err
 	}
-	return json.Unmarshal(body, target)
+	return resp.Header.Get("ETag"), contentHash(body), json.Unmarshal(body, target)
 }
 
-func (s *Store) loadOrFetch(filename string, url string, target interface{}) error {
-	localPath := filepath.Join(s.localDataPath, filename)
-	if _, err := os.Stat(localPath); err == nil {
-		content, err := os.ReadFile(localPath)
-		if err == nil {
-			if err := json.Unmarshal(content, target); err == nil {
-				fmt.Printf("Loaded %s from local file\n", filename)
-				return nil
-			} else {
-				fmt.Printf("Warning: failed to unmarshal local %s: %v. Falling back to remote.\n", filename, err)
-			}
-		} else {
-			fmt.Printf("Warning: failed to read local %s: %v. Falling back to remote.\n", filename, err)
-		}
-	}
-	return fetchJSON(url, target)
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
 }
 
-// Fetch loads all master data from local files or remote
-func (s *Store) Fetch() error {
-	fmt.Println("Updating master data...")
+// errInvalidLocal marks a local file that exists but could not be read or parsed
+var errInvalidLocal = errors.New("invalid local file")
 
-	var events []models.Event
-	if err := s.loadOrFetch("events.json", EventsURL, &events); err != nil {
-		return fmt.Errorf("fetch events: %v", err)
-	}
-
-	var eventCards []models.EventCard
-	if err := s.loadOrFetch("eventCards.json", EventCardsURL, &eventCards); err != nil {
-		return fmt.Errorf("fetch eventCards: %v", err)
-	}
+// loadOptions controls where load reads master files from
+type loadOptions struct {
+	// preferRemote fetches remote files first and uses local files only as fallback
+	preferRemote bool
+	// strictLocal aborts the load instead of falling back when a local file is corrupt
+	strictLocal bool
+}
 
-	var eventMusics []models.EventMusic
-	if err := s.loadOrFetch("eventMusics.json", EventMusicsURL, &eventMusics); err != nil {
-		return fmt.Errorf("fetch eventMusics: %v", err)
+// loadOrFetch decodes a master file into target and reports where it came from
+func (s *Store) loadOrFetch(ctx context.Context, filename, remotePath string, target interface{}, opts loadOptions) (models.DataSource, error) {
+	if opts.preferRemote && len(s.baseURLs) > 0 {
+		src, err := s.fetchRemote(ctx, remotePath, target)
+		if err != nil {
+			if src, ok, _ := s.loadLocal(filename, target); ok {
+				return src, nil
+			}
+		}
+		return src, err
 	}
-
-	var virtualLives []models.VirtualLive
-	if err := s.loadOrFetch("virtualLives.json", VirtualLivesURL, &virtualLives); err != nil {
-		fmt.Printf("Warning: failed to fetch virtualLives: %v\n", err)
+	src, ok, err := s.loadLocal(filename, target)
+	if ok {
+		return src, nil
 	}
-
-	var gachas []models.Gacha
-	if err := s.loadOrFetch("gachas.json", GachasURL, &gachas); err != nil {
-		fmt.Printf("Warning: failed to fetch gachas: %v\n", err)
+	if err != nil && opts.strictLocal {
+		return src, fmt.Errorf("%w %s: %v", errInvalidLocal, filename, err)
 	}
-
-	var cardCostume3ds []models.CardCostume3d
-	if err := s.loadOrFetch("cardCostume3ds.json", CardCostume3dsURL, &cardCostume3ds); err != nil {
-		fmt.Printf("Warning: failed to fetch cardCostume3ds: %v\n", err)
+	if len(s.baseURLs) == 0 {
+		return src, fmt.Errorf("no local file and no remote configured for %s", filename)
 	}
+	return s.fetchRemote(ctx, remotePath, target)
+}
 
-	var costume3ds []models.Costume3d
-	if err := s.loadOrFetch("costume3ds.json", Costume3dsURL, &costume3ds); err != nil {
-		fmt.Printf("Warning: failed to fetch costume3ds: %v\n", err)
+// loadLocal decodes a local master file. It reports false with a nil error
+// when the file does not exist.
+func (s *Store) loadLocal(filename string, target interface{}) (models.DataSource, bool, error) {
+	localPath := filepath.Join(s.localDataPath, filename)
+	src := models.DataSource{Type: models.DataSourceLocal, Location: localPath}
+	if _, err := os.Stat(localPath); err != nil {
+		return src, false, nil
 	}
-
-	// Build Maps
-	newCardEventMap := make(map[int]models.EventInfo)
-	newMusicEventMap := make(map[int][]models.EventInfo)
-	eventLookup := make(map[int]models.Event)
-	for _, e := range events {
-		eventLookup[e.ID] = e
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		fmt.Printf("[%s] Warning: failed to read local %s: %v\n", s.region, filename, err)
+		return src, false, err
 	}
-
-	for _, ec := range eventCards {
-		if ev, ok := eventLookup[ec.EventID]; ok {
-			if existing, exists := newCardEventMap[ec.CardID]; exists {
-				if ev.ID < existing.ID {
-					newCardEventMap[ec.CardID] = models.EventInfo{
-						ID:              ev.ID,
-						Name:            ev.Name,
-						AssetbundleName: ev.AssetbundleName,
-					}
-				}
-			} else {
-				newCardEventMap[ec.CardID] = models.EventInfo{
-					ID:              ev.ID,
-					Name:            ev.Name,
-					AssetbundleName: ev.AssetbundleName,
-				}
-			}
-		}
+	if err := json.Unmarshal(content, target); err != nil {
+		fmt.Printf("[%s] Warning: failed to unmarshal local %s: %v\n", s.region, filename, err)
+		return src, false, err
 	}
+	src.SHA256 = contentHash(content)
+	fmt.Printf("[%s] Loaded %s from local file\n", s.region, filename)
+	return src, true, nil
+}
 
-	for _, em := range eventMusics {
-		if ev, ok := eventLookup[em.EventID]; ok {
-			info := models.EventInfo{
-				ID:              ev.ID,
-				Name:            ev.Name,
-				AssetbundleName: ev.AssetbundleName,
-			}
-			newMusicEventMap[em.MusicID] = append(newMusicEventMap[em.MusicID], info)
+func (s *Store) fetchRemote(ctx context.Context, remotePath string, target interface{}) (models.DataSource, error) {
+	var lastErr error
+	for _, base := range s.baseURLs {
+		url := remoteFileURL(base, remotePath)
+		etag, sum, err := fetchJSON(ctx, url, target)
+		if lastErr = err; err == nil {
+			return models.DataSource{Type: models.DataSourceRemote, Location: url, ETag: etag, SHA256: sum}, nil
 		}
+		fmt.Printf("[%s] Warning: failed to fetch %s: %v\n", s.region, url, lastErr)
 	}
+	return models.DataSource{Type: models.DataSourceRemote}, lastErr
+}
 
-	// Build Virtual Live <-> Event mappings
-	virtualLiveLookup := make(map[int]models.VirtualLive)
-	for _, vl := range virtualLives {
-		virtualLiveLookup[vl.ID] = vl
-	}
+func remoteFileURL(base, filename string) string {
+	return strings.TrimSuffix(base, "/") + "/" + filename
+}
 
-	newEventVirtualLiveMap := make(map[int]models.VirtualLiveInfo)
-	newVirtualLiveEventMap := make(map[int]models.EventInfo)
-	for _, e := range events {
-		if e.VirtualLiveId > 0 {
-			if vl, ok := virtualLiveLookup[e.VirtualLiveId]; ok {
-				newEventVirtualLiveMap[e.ID] = models.VirtualLiveInfo{
-					ID:              vl.ID,
-					Name:            vl.Name,
-					AssetbundleName: vl.AssetbundleName,
-				}
-				newVirtualLiveEventMap[e.VirtualLiveId] = models.EventInfo{
-					ID:              e.ID,
-					Name:            e.Name,
-					AssetbundleName: e.AssetbundleName,
-				}
-			}
-		}
-	}
+// Fetch loads all master data from local files or remote
+func (s *Store) Fetch() error {
+	return s.load(context.Background(), loadOptions{})
+}
 
-	newGachaPickups := make(map[int][]int)
-	for _, g := range gachas {
-		for _, p := range g.GachaPickups {
-			newGachaPickups[g.ID] = append(newGachaPickups[g.ID], p.CardID)
-		}
-	}
+func (s *Store) load(ctx context.Context, opts loadOptions) error {
+	s.loadMutex.Lock()
+	defer s.loadMutex.Unlock()
 
-	newCardGachaMap := make(map[int][]models.GachaInfo)
-	for _, g := range gachas {
-		info := models.GachaInfo{
-			ID:              g.ID,
-			Name:            g.Name,
-			AssetbundleName: g.AssetbundleName,
-		}
-		for _, p := range g.GachaPickups {
-			newCardGachaMap[p.CardID] = append(newCardGachaMap[p.CardID], info)
-		}
+	fmt.Printf("[%s] Updating master data...\n", s.region)
+	snap, err := s.loadTables(ctx, opts)
+	if err != nil {
+		return err
 	}
-
-	// Build Costume Maps
-	newCardCostume3dMap := make(map[int][]int)
-	newCostume3dGroupIdMap := make(map[int]int)
-	newCostume3dGroupMap := make(map[int][]models.Costume3d)
-
-	for _, cc := range cardCostume3ds {
-		newCardCostume3dMap[cc.CardID] = append(newCardCostume3dMap[cc.CardID], cc.Costume3dID)
+	snap.buildIndexes()
+	if err := snap.encodeResponses(); err != nil {
+		return err
 	}
 
-	for _, c := range costume3ds {
-		newCostume3dGroupIdMap[c.ID] = c.Costume3dGroupId
-		newCostume3dGroupMap[c.Costume3dGroupId] = append(newCostume3dGroupMap[c.Costume3dGroupId], c)
-	}
+	version, versionSource := s.resolveVersion(ctx, snap.VersionInfo.Sources)
+	snap.VersionInfo.Region = string(s.region)
+	snap.VersionInfo.Version = version
+	snap.VersionInfo.VersionSource = versionSource
+	snap.VersionInfo.LoadedAt = time.Now().UnixMilli()
+	snap.ETag = snap.contentETag()
 
 	// Update store atomically
 	s.mutex.Lock()
-	s.CardEventMap = newCardEventMap
-	s.MusicEventMap = newMusicEventMap
-	s.CardGachaMap = newCardGachaMap
-	s.EventVirtualLiveMap = newEventVirtualLiveMap
-	s.VirtualLiveEventMap = newVirtualLiveEventMap
-	s.GachaList = gachas
-	s.GachaPickups = newGachaPickups
-	s.CardCostume3dMap = newCardCostume3dMap
-	s.Costume3dGroupIdMap = newCostume3dGroupIdMap
-	s.Costume3dGroupMap = newCostume3dGroupMap
+	if snap.ETag == s.current.ETag {
+		snap.ModifiedAt = s.current.ModifiedAt
+	} else {
+		snap.ModifiedAt = time.UnixMilli(snap.VersionInfo.LoadedAt).UTC().Truncate(time.Second)
+	}
+	s.recordChangelog(s.current, snap)
+	s.current = snap
 	s.mutex.Unlock()
 
-	fmt.Printf("Data updated. Mapped %d cards, %d musics, %d event-vl, loaded %d gachas, %d costumes.\n",
-		len(newCardEventMap), len(newMusicEventMap), len(newEventVirtualLiveMap), len(gachas), len(costume3ds))
+	fmt.Printf("[%s] Data updated. Mapped %d cards, %d musics, %d event-vl, loaded %d gachas, %d costumes.\n",
+		s.region, len(snap.CardEventMap), len(snap.MusicEventMap), len(snap.EventVirtualLiveMap),
+		len(Gachas.Rows(snap)), len(Costume3ds.Rows(snap)))
 	return nil
 }
 
-// StartPeriodicUpdate starts a goroutine to update data periodically
-func (s *Store) StartPeriodicUpdate(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	go func() {
-		for range ticker.C {
-			if err := s.Fetch(); err != nil {
-				fmt.Printf("Periodic update error: %v\n", err)
+// loadTables loads every registered table concurrently into a new snapshot.
+// Failing required tables, or corrupt local files in strict mode, abort the load;
+// other failures leave the table empty.
+func (s *Store) loadTables(ctx context.Context, opts loadOptions) (*Snapshot, error) {
+	type result struct {
+		rows  any
+		count int
+		src   models.DataSource
+		err   error
+	}
+	results := make([]result, len(registry))
+
+	var wg sync.WaitGroup
+	for i, t := range registry {
+		wg.Add(1)
+		go func(i int, t tableDef) {
+			defer wg.Done()
+			r := &results[i]
+			r.rows, r.count, r.src, r.err = t.load(ctx, s, opts)
+		}(i, t)
+	}
+	wg.Wait()
+
+	snap := newSnapshot()
+	snap.VersionInfo.Sources = make(map[string]models.DataSource)
+	snap.VersionInfo.Counts = make(map[string]int)
+	for i, t := range registry {
+		r := results[i]
+		snap.VersionInfo.Counts[t.name()] = r.count
+		if r.err != nil {
+			if t.required() || errors.Is(r.err, errInvalidLocal) {
+				return nil, fmt.Errorf("fetch %s: %w", t.name(), r.err)
 			}
+			fmt.Printf("[%s] Warning: failed to fetch %s: %v\n", s.region, t.name(), r.err)
+			continue
 		}
-	}()
+		snap.tables[t.name()] = r.rows
+		snap.VersionInfo.Sources[t.file()] = r.src
+	}
+	return snap, nil
 }
 
 // Thread-safe getters
 
-func (s *Store) GetCardEventMap() map[int]models.EventInfo {
+// Snapshot returns the current snapshot. All reads from it are consistent
+// with each other even if a reload happens meanwhile.
+func (s *Store) Snapshot() *Snapshot {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
-	return s.CardEventMap
+	return s.current
+}
+
+func (s *Store) GetCardEventMap() map[int]models.EventInfo {
+	return s.Snapshot().CardEventMap
 }
 
 func (s *Store) GetMusicEventMap() map[int][]models.EventInfo {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.MusicEventMap
+	return s.Snapshot().MusicEventMap
 }
 
 func (s *Store) GetCardGachaMap() map[int][]models.GachaInfo {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.CardGachaMap
+	return s.Snapshot().CardGachaMap
 }
 
 func (s *Store) GetEventVirtualLiveMap() map[int]models.VirtualLiveInfo {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.EventVirtualLiveMap
+	return s.Snapshot().EventVirtualLiveMap
 }
 
 func (s *Store) GetVirtualLiveEventMap() map[int]models.EventInfo {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.VirtualLiveEventMap
+	return s.Snapshot().VirtualLiveEventMap
 }
 
 func (s *Store) GetGachaList() []models.Gacha {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.GachaList
+	return Gachas.Rows(s.Snapshot())
 }
 
 func (s *Store) GetGachaPickups() map[int][]int {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.GachaPickups
+	return s.Snapshot().GachaPickups
 }
 
 func (s *Store) GetCardCostume3dMap() map[int][]int {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.CardCostume3dMap
+	return s.Snapshot().CardCostume3dMap
 }
 
 func (s *Store) GetCostume3dGroupIdMap() map[int]int {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.Costume3dGroupIdMap
+	return s.Snapshot().Costume3dGroupIdMap
 }
 
 func (s *Store) GetCostume3dGroupMap() map[int][]models.Costume3d {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	return s.Costume3dGroupMap
+	return s.Snapshot().Costume3dGroupMap
+}
+
+func (s *Store) GetVersionInfo() models.MasterVersionInfo {
+	return s.Snapshot().VersionInfo
 }
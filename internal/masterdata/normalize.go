@@ -0,0 +1,63 @@
+package masterdata
+
+import (
+	"strings"
+	"unicode"
+)
+
+// halfwidthKatakana maps U+FF61..U+FF9D to their full-width forms
+var halfwidthKatakana = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン")
+
+const (
+	voicedBases     = "カキクケコサシスセソタチツテトハヒフヘホ"
+	semiVoicedBases = "ハヒフヘホ"
+)
+
+// NormalizeText folds text for searching: full-width ASCII and half-width
+// katakana become their canonical widths, hiragana becomes katakana, letters
+// are lowercased and runs of whitespace collapse to a single space.
+func NormalizeText(s string) string {
+	out := make([]rune, 0, len(s))
+	space := false
+	for _, r := range s {
+		var prev rune
+		if len(out) > 0 {
+			prev = out[len(out)-1]
+		}
+		switch {
+		case r == 0x3000 || unicode.IsSpace(r):
+			space = len(out) > 0
+			continue
+		case r >= 0xFF01 && r <= 0xFF5E:
+			r -= 0xFEE0
+		case r >= 0xFF61 && r <= 0xFF9D:
+			r = halfwidthKatakana[r-0xFF61]
+		case r == 0xFF9E || r == 0x3099 || r == 0x309B:
+			// Combine dakuten with the preceding kana
+			if prev == 'ウ' {
+				out[len(out)-1] = 'ヴ'
+				continue
+			}
+			if strings.ContainsRune(voicedBases, prev) {
+				out[len(out)-1] = prev + 1
+				continue
+			}
+			r = 0x309B
+		case r == 0xFF9F || r == 0x309A || r == 0x309C:
+			// Combine handakuten with the preceding kana
+			if strings.ContainsRune(semiVoicedBases, prev) {
+				out[len(out)-1] = prev + 2
+				continue
+			}
+			r = 0x309C
+		case r >= 0x3041 && r <= 0x3096:
+			r += 0x60
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
@@ -0,0 +1,163 @@
+package masterdata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"snowy_viewer/internal/models"
+)
+
+// Refresh reloads master data from remote if the remote copy has changed.
+// It returns whether a new snapshot was loaded.
+func (s *Store) Refresh(ctx context.Context) (bool, error) {
+	changed, reason, err := s.remoteChanged(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		return false, nil
+	}
+
+	before := s.GetVersionInfo()
+	if err := s.load(ctx, loadOptions{preferRemote: true}); err != nil {
+		return false, err
+	}
+	after := s.GetVersionInfo()
+	fmt.Printf("[%s] Master data refreshed (%s): %s\n", s.region, reason, summarizeChanges(before, after))
+	return true, nil
+}
+
+// StartPeriodicUpdate refreshes data every interval until ctx is cancelled
+func (s *Store) StartPeriodicUpdate(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+					fmt.Printf("[%s] Periodic update error: %v\n", s.region, err)
+				}
+			}
+		}
+	}()
+}
+
+// StartPeriodicUpdate starts the background refresher of every region
+func (s *Stores) StartPeriodicUpdate(ctx context.Context, interval time.Duration) {
+	for _, region := range s.regions {
+		s.stores[region].StartPeriodicUpdate(ctx, interval)
+	}
+}
+
+// remoteChanged compares the remote version file, or the file ETags when
+// no version is published, against the loaded snapshot. An older dotted
+// remote version is not a change.
+func (s *Store) remoteChanged(ctx context.Context) (bool, string, error) {
+	if len(s.baseURLs) == 0 {
+		return false, "", nil
+	}
+	current := s.GetVersionInfo()
+
+	if version, _, err := s.fetchRemoteVersion(ctx); err == nil {
+		if version == current.Version {
+			return false, "", nil
+		}
+		// Like the file watcher, never roll back to an older version, e.g.
+		// when local files are ahead of the remote. Versions without an order
+		// only change when they differ.
+		if c, ok := compareVersions(version, current.Version); ok && c < 0 {
+			return false, "", nil
+		}
+		return true, fmt.Sprintf("version %s -> %s", current.Version, version), nil
+	}
+
+	var changed []string
+	checked := 0
+	for filename, src := range current.Sources {
+		etag, err := s.remoteETag(ctx, filename, src)
+		if err != nil {
+			continue
+		}
+		checked++
+		if etag != src.ETag {
+			changed = append(changed, filename)
+		}
+	}
+	if checked == 0 {
+		return false, "", fmt.Errorf("neither remote version nor ETags available")
+	}
+	if len(changed) == 0 {
+		return false, "", nil
+	}
+	sort.Strings(changed)
+	return true, "etag changed: " + strings.Join(changed, ", "), nil
+}
+
+// remoteETag issues a HEAD request for a master file. Files already loaded
+// from remote are checked at the URL they came from.
+func (s *Store) remoteETag(ctx context.Context, filename string, src models.DataSource) (string, error) {
+	urls := make([]string, 0, len(s.baseURLs)+1)
+	if src.Type == models.DataSourceRemote {
+		urls = append(urls, src.Location)
+	}
+	remotePath := filename
+	if t, ok := tableByFile(filename); ok {
+		remotePath = t.remotePath()
+	}
+	for _, base := range s.baseURLs {
+		urls = append(urls, remoteFileURL(base, remotePath))
+	}
+
+	var lastErr error
+	for _, url := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("bad status: %s", resp.Status)
+			continue
+		}
+		etag := resp.Header.Get("ETag")
+		if etag == "" {
+			lastErr = fmt.Errorf("no ETag for %s", url)
+			continue
+		}
+		return etag, nil
+	}
+	return "", lastErr
+}
+
+// summarizeChanges describes the table size differences of two snapshots
+func summarizeChanges(before, after models.MasterVersionInfo) string {
+	var parts []string
+	names := make([]string, 0, len(after.Counts))
+	for name := range after.Counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prev, now := before.Counts[name], after.Counts[name]
+		if prev != now {
+			parts = append(parts, fmt.Sprintf("%s %d -> %d (%+d)", name, prev, now, now-prev))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "no table size changes"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+package masterdata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Region identifies a game server whose master data is served
+type Region string
+
+const (
+	RegionJP Region = "jp"
+	RegionCN Region = "cn"
+	RegionTW Region = "tw"
+	RegionEN Region = "en"
+	RegionKR Region = "kr"
+)
+
+// AllRegions lists every supported region in display order
+var AllRegions = []Region{RegionJP, RegionCN, RegionTW, RegionEN, RegionKR}
+
+// DefaultBaseURLs are the remote master data locations per region, tried in order
+var DefaultBaseURLs = map[Region][]string{
+	RegionJP: {
+		"https://sekaimaster.exmeaning.com/master",
+		"https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-master/main/master",
+	},
+	RegionCN: {
+		"https://sekaimaster-cn.exmeaning.com/master",
+		"https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-sc-master/main/master",
+	},
+	RegionTW: {
+		"https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-tc-master/main/master",
+	},
+	RegionEN: {
+		"https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-en-master/main/master",
+	},
+	RegionKR: {
+		"https://raw.githubusercontent.com/Team-Haruki/haruki-sekai-kr-master/main/master",
+	},
+}
+
+// ParseRegion validates a region name
+func ParseRegion(name string) (Region, bool) {
+	r := Region(strings.ToLower(strings.TrimSpace(name)))
+	for _, known := range AllRegions {
+		if r == known {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// RegionConfig describes where a region's master data is loaded from
+type RegionConfig struct {
+	Region        Region
+	LocalDataPath string
+	BaseURLs      []string
+}
+
+// Stores holds one master data store per configured region
+type Stores struct {
+	stores        map[Region]*Store
+	regions       []Region
+	defaultRegion Region
+}
+
+// NewStores creates a store for each region config.
+// The default region falls back to the first config if it is not configured.
+func NewStores(defaultRegion Region, configs ...RegionConfig) *Stores {
+	s := &Stores{
+		stores:        make(map[Region]*Store),
+		defaultRegion: defaultRegion,
+	}
+	for _, cfg := range configs {
+		if _, exists := s.stores[cfg.Region]; exists {
+			continue
+		}
+		baseURLs := cfg.BaseURLs
+		if len(baseURLs) == 0 {
+			baseURLs = DefaultBaseURLs[cfg.Region]
+		}
+		s.stores[cfg.Region] = NewStore(cfg.Region, cfg.LocalDataPath, baseURLs)
+		s.regions = append(s.regions, cfg.Region)
+	}
+	if _, ok := s.stores[s.defaultRegion]; !ok && len(s.regions) > 0 {
+		s.defaultRegion = s.regions[0]
+	}
+	return s
+}
+
+// Get returns the store for a region
+func (s *Stores) Get(region Region) (*Store, bool) {
+	store, ok := s.stores[region]
+	return store, ok
+}
+
+// Default returns the store for the default region
+func (s *Stores) Default() *Store {
+	return s.stores[s.defaultRegion]
+}
+
+// DefaultRegion returns the region used when a request does not specify one
+func (s *Stores) DefaultRegion() Region {
+	return s.defaultRegion
+}
+
+// Regions returns the configured regions in config order
+func (s *Stores) Regions() []Region {
+	return s.regions
+}
+
+// FetchAll loads every region concurrently
+func (s *Stores) FetchAll() error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.regions))
+	for i, region := range s.regions {
+		wg.Add(1)
+		go func(i int, store *Store) {
+			defer wg.Done()
+			if err := store.Fetch(); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", store.Region(), err)
+			}
+		}(i, s.stores[region])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
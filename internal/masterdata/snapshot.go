@@ -0,0 +1,171 @@
+package masterdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"snowy_viewer/internal/models"
+)
+
+// Snapshot is an immutable view of one load of master data.
+// Callers must not modify the maps and slices it exposes.
+type Snapshot struct {
+	// Raw table rows keyed by table name, read through Table.Rows
+	tables map[string]any
+
+	EventByID          map[int]models.Event
+	EventCardsByEvent  map[int][]models.EventCard
+	EventMusicsByEvent map[int][]models.EventMusic
+
+	// Card/Event/Music mappings
+	CardEventMap  map[int]models.EventInfo
+	MusicEventMap map[int][]models.EventInfo
+	CardGachaMap  map[int][]models.GachaInfo
+
+	// Event <-> VirtualLive mappings
+	VirtualLiveByID     map[int]models.VirtualLive
+	EventVirtualLiveMap map[int]models.VirtualLiveInfo
+	VirtualLiveEventMap map[int]models.EventInfo
+
+	// Gacha data
+	GachaByID    map[int]models.Gacha
+	GachaPickups map[int][]int
+
+	// Costume mappings
+	CardCostume3dMap    map[int][]int
+	Costume3dGroupIdMap map[int]int
+	Costume3dGroupMap   map[int][]models.Costume3d
+
+	// Character and card lookups; card lists hold card IDs in master order
+	CharacterByID    map[int]models.GameCharacter
+	CharactersByUnit map[string][]int
+	CardByID         map[int]models.Card
+	CardsByCharacter map[int][]int
+	CardsByUnit      map[string][]int
+	SkillByID        map[int]models.Skill
+
+	CardSupplyTypeByID map[int]string
+
+	// Music lookups; difficulties are ordered easy to append
+	MusicByID                map[int]models.Music
+	MusicDifficultiesByMusic map[int][]models.MusicDifficulty
+
+	// Text is the search index over names, titles and readings
+	Text *TextIndex
+
+	// Pre-encoded response bodies, read through Encoded
+	encoded map[string]*EncodedJSON
+
+	VersionInfo models.MasterVersionInfo
+
+	// ETag identifies the snapshot content; ModifiedAt is when it last changed.
+	// Both are empty until the first load.
+	ETag       string
+	ModifiedAt time.Time
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{
+		tables:              make(map[string]any),
+		EventByID:           make(map[int]models.Event),
+		EventCardsByEvent:   make(map[int][]models.EventCard),
+		EventMusicsByEvent:  make(map[int][]models.EventMusic),
+		CardEventMap:        make(map[int]models.EventInfo),
+		MusicEventMap:       make(map[int][]models.EventInfo),
+		CardGachaMap:        make(map[int][]models.GachaInfo),
+		VirtualLiveByID:     make(map[int]models.VirtualLive),
+		EventVirtualLiveMap: make(map[int]models.VirtualLiveInfo),
+		VirtualLiveEventMap: make(map[int]models.EventInfo),
+		GachaByID:           make(map[int]models.Gacha),
+		GachaPickups:        make(map[int][]int),
+		CardCostume3dMap:    make(map[int][]int),
+		Costume3dGroupIdMap: make(map[int]int),
+		Costume3dGroupMap:   make(map[int][]models.Costume3d),
+
+		CharacterByID:            make(map[int]models.GameCharacter),
+		CharactersByUnit:         make(map[string][]int),
+		CardByID:                 make(map[int]models.Card),
+		CardsByCharacter:         make(map[int][]int),
+		CardsByUnit:              make(map[string][]int),
+		SkillByID:                make(map[int]models.Skill),
+		CardSupplyTypeByID:       make(map[int]string),
+		MusicByID:                make(map[int]models.Music),
+		MusicDifficultiesByMusic: make(map[int][]models.MusicDifficulty),
+		Text:                     newTextIndex(),
+		encoded:                  make(map[string]*EncodedJSON),
+	}
+}
+
+// buildIndexes runs every registered index builder in registration order,
+// then builds the text index over the finished lookups
+func (snap *Snapshot) buildIndexes() {
+	for _, t := range registry {
+		for _, build := range t.indexes() {
+			build(snap)
+		}
+	}
+	buildTextIndex(snap)
+}
+
+// schemaVersion is mixed into snapshot ETags. Bump it when a response shape
+// changes, so that clients do not revalidate bodies of the old shape.
+const schemaVersion = 2
+
+// buildID identifies the running binary, so that every deploy also changes
+// snapshot ETags: the VCS revision when the build recorded one, else the hash
+// of the executable
+var buildID = readBuildID()
+
+func readBuildID() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		revision, modified := "", ""
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" && modified != "true" {
+			return revision
+		}
+	}
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentETag derives a weak ETag from the region, the response schema and
+// build, and the content hash of every loaded table, so reloading identical
+// files keeps the same ETag while a deploy changes it
+func (snap *Snapshot) contentETag() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", snap.VersionInfo.Region)
+	fmt.Fprintf(h, "schema %d build %s\n", schemaVersion, buildID)
+	for _, t := range registry {
+		src, ok := snap.VersionInfo.Sources[t.file()]
+		if !ok {
+			fmt.Fprintf(h, "%s -\n", t.name())
+			continue
+		}
+		fmt.Fprintf(h, "%s %s\n", t.name(), src.SHA256)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
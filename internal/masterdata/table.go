@@ -0,0 +1,121 @@
+package masterdata
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"snowy_viewer/internal/models"
+)
+
+// IndexFunc derives lookup maps on a snapshot from its loaded tables
+type IndexFunc func(snap *Snapshot)
+
+// TableSpec declares a master data table whose rows decode into T
+type TableSpec[T any] struct {
+	// Name identifies the table in counts and logs, e.g. "events"
+	Name string
+	// File is the local file name under the data path, e.g. "events.json"
+	File string
+	// RemotePath is the path relative to a remote base URL; defaults to File
+	RemotePath string
+	// Required tables abort the load when they cannot be fetched
+	Required bool
+	// Indexes run in registration order once every table is loaded
+	Indexes []IndexFunc
+}
+
+// Table is a registered master data table
+type Table[T any] struct {
+	spec TableSpec[T]
+}
+
+// tableDef is the type-erased view of a Table used by the loader
+type tableDef interface {
+	name() string
+	file() string
+	remotePath() string
+	required() bool
+	rowType() reflect.Type
+	indexes() []IndexFunc
+	load(ctx context.Context, s *Store, opts loadOptions) (any, int, models.DataSource, error)
+}
+
+// registry holds every registered table in registration order
+var registry []tableDef
+
+// Register adds a table to the registry. It panics on duplicate names or files.
+func Register[T any](spec TableSpec[T]) *Table[T] {
+	if spec.RemotePath == "" {
+		spec.RemotePath = spec.File
+	}
+	for _, t := range registry {
+		if t.name() == spec.Name || t.file() == spec.File {
+			panic(fmt.Sprintf("masterdata: table %s (%s) registered twice", spec.Name, spec.File))
+		}
+	}
+	t := &Table[T]{spec: spec}
+	registry = append(registry, t)
+	return t
+}
+
+// Rows returns the table's rows in a snapshot, or nil if it was not loaded
+func (t *Table[T]) Rows(snap *Snapshot) []T {
+	rows, _ := snap.tables[t.spec.Name].([]T)
+	return rows
+}
+
+// Name returns the table name
+func (t *Table[T]) Name() string {
+	return t.spec.Name
+}
+
+func (t *Table[T]) name() string          { return t.spec.Name }
+func (t *Table[T]) file() string          { return t.spec.File }
+func (t *Table[T]) remotePath() string    { return t.spec.RemotePath }
+func (t *Table[T]) required() bool        { return t.spec.Required }
+func (t *Table[T]) indexes() []IndexFunc  { return t.spec.Indexes }
+func (t *Table[T]) rowType() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+func (t *Table[T]) load(ctx context.Context, s *Store, opts loadOptions) (any, int, models.DataSource, error) {
+	var rows []T
+	src, err := s.loadOrFetch(ctx, t.spec.File, t.spec.RemotePath, &rows, opts)
+	if err != nil {
+		return nil, 0, src, err
+	}
+	return rows, len(rows), src, nil
+}
+
+// tableByFile looks up a registered table by its local file name
+func tableByFile(file string) (tableDef, bool) {
+	for _, t := range registry {
+		if t.file() == file {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// TableInfo describes a registered table
+type TableInfo struct {
+	Name       string
+	File       string
+	RemotePath string
+	Required   bool
+	RowType    reflect.Type
+}
+
+// RegisteredTables lists the registry in load order
+func RegisteredTables() []TableInfo {
+	infos := make([]TableInfo, len(registry))
+	for i, t := range registry {
+		infos[i] = TableInfo{
+			Name:       t.name(),
+			File:       t.file(),
+			RemotePath: t.remotePath(),
+			Required:   t.required(),
+			RowType:    t.rowType(),
+		}
+	}
+	return infos
+}
@@ -0,0 +1,271 @@
+package masterdata
+
+import (
+	"sort"
+
+	"snowy_viewer/internal/models"
+)
+
+// Registered master tables
+var (
+	Events         *Table[models.Event]
+	EventCards     *Table[models.EventCard]
+	EventMusics    *Table[models.EventMusic]
+	VirtualLives   *Table[models.VirtualLive]
+	Gachas         *Table[models.Gacha]
+	CardCostume3ds *Table[models.CardCostume3d]
+	Costume3ds     *Table[models.Costume3d]
+
+	GameCharacters    *Table[models.GameCharacter]
+	CardSupplies      *Table[models.CardSupply]
+	Cards             *Table[models.Card]
+	Skills            *Table[models.Skill]
+	Musics            *Table[models.Music]
+	MusicDifficulties *Table[models.MusicDifficulty]
+)
+
+// musicDifficultyOrder sorts difficulties from easiest to hardest
+var musicDifficultyOrder = map[string]int{
+	"easy":   0,
+	"normal": 1,
+	"hard":   2,
+	"expert": 3,
+	"master": 4,
+	"append": 5,
+}
+
+func init() {
+	Events = Register(TableSpec[models.Event]{
+		Name:     "events",
+		File:     "events.json",
+		Required: true,
+		Indexes:  []IndexFunc{buildEventLookup},
+	})
+	EventCards = Register(TableSpec[models.EventCard]{
+		Name:     "eventCards",
+		File:     "eventCards.json",
+		Required: true,
+		Indexes:  []IndexFunc{buildCardEventMap},
+	})
+	EventMusics = Register(TableSpec[models.EventMusic]{
+		Name:     "eventMusics",
+		File:     "eventMusics.json",
+		Required: true,
+		Indexes:  []IndexFunc{buildMusicEventMap},
+	})
+	VirtualLives = Register(TableSpec[models.VirtualLive]{
+		Name:    "virtualLives",
+		File:    "virtualLives.json",
+		Indexes: []IndexFunc{buildVirtualLiveMaps},
+	})
+	Gachas = Register(TableSpec[models.Gacha]{
+		Name:    "gachas",
+		File:    "gachas.json",
+		Indexes: []IndexFunc{buildGachaMaps},
+	})
+	CardCostume3ds = Register(TableSpec[models.CardCostume3d]{
+		Name:    "cardCostume3ds",
+		File:    "cardCostume3ds.json",
+		Indexes: []IndexFunc{buildCardCostume3dMap},
+	})
+	Costume3ds = Register(TableSpec[models.Costume3d]{
+		Name:    "costume3ds",
+		File:    "costume3ds.json",
+		Indexes: []IndexFunc{buildCostume3dGroupMaps},
+	})
+	GameCharacters = Register(TableSpec[models.GameCharacter]{
+		Name:    "gameCharacters",
+		File:    "gameCharacters.json",
+		Indexes: []IndexFunc{buildCharacterMaps},
+	})
+	CardSupplies = Register(TableSpec[models.CardSupply]{
+		Name:    "cardSupplies",
+		File:    "cardSupplies.json",
+		Indexes: []IndexFunc{buildCardSupplyMap},
+	})
+	// Cards index after characters to resolve card units
+	Cards = Register(TableSpec[models.Card]{
+		Name:    "cards",
+		File:    "cards.json",
+		Indexes: []IndexFunc{buildCardMaps},
+	})
+	Skills = Register(TableSpec[models.Skill]{
+		Name:    "skills",
+		File:    "skills.json",
+		Indexes: []IndexFunc{buildSkillMap},
+	})
+	Musics = Register(TableSpec[models.Music]{
+		Name:    "musics",
+		File:    "musics.json",
+		Indexes: []IndexFunc{buildMusicMap},
+	})
+	MusicDifficulties = Register(TableSpec[models.MusicDifficulty]{
+		Name:    "musicDifficulties",
+		File:    "musicDifficulties.json",
+		Indexes: []IndexFunc{buildMusicDifficultyMap},
+	})
+}
+
+func eventInfo(e models.Event) models.EventInfo {
+	return models.EventInfo{
+		ID:              e.ID,
+		Name:            e.Name,
+		AssetbundleName: e.AssetbundleName,
+	}
+}
+
+func buildEventLookup(snap *Snapshot) {
+	for _, e := range Events.Rows(snap) {
+		snap.EventByID[e.ID] = e
+	}
+}
+
+func buildCardEventMap(snap *Snapshot) {
+	for _, ec := range EventCards.Rows(snap) {
+		snap.EventCardsByEvent[ec.EventID] = append(snap.EventCardsByEvent[ec.EventID], ec)
+		if ev, ok := snap.EventByID[ec.EventID]; ok {
+			if existing, exists := snap.CardEventMap[ec.CardID]; !exists || ev.ID < existing.ID {
+				snap.CardEventMap[ec.CardID] = eventInfo(ev)
+			}
+		}
+	}
+}
+
+func buildMusicEventMap(snap *Snapshot) {
+	for _, em := range EventMusics.Rows(snap) {
+		snap.EventMusicsByEvent[em.EventID] = append(snap.EventMusicsByEvent[em.EventID], em)
+		if ev, ok := snap.EventByID[em.EventID]; ok {
+			snap.MusicEventMap[em.MusicID] = append(snap.MusicEventMap[em.MusicID], eventInfo(ev))
+		}
+	}
+	for _, musics := range snap.EventMusicsByEvent {
+		sort.SliceStable(musics, func(i, j int) bool { return musics[i].Seq < musics[j].Seq })
+	}
+}
+
+// buildVirtualLiveMaps links events and virtual lives in both directions
+func buildVirtualLiveMaps(snap *Snapshot) {
+	for _, vl := range VirtualLives.Rows(snap) {
+		snap.VirtualLiveByID[vl.ID] = vl
+	}
+
+	for _, e := range Events.Rows(snap) {
+		if e.VirtualLiveId > 0 {
+			if vl, ok := snap.VirtualLiveByID[e.VirtualLiveId]; ok {
+				snap.EventVirtualLiveMap[e.ID] = models.VirtualLiveInfo{
+					ID:              vl.ID,
+					Name:            vl.Name,
+					AssetbundleName: vl.AssetbundleName,
+				}
+				snap.VirtualLiveEventMap[e.VirtualLiveId] = eventInfo(e)
+			}
+		}
+	}
+}
+
+func buildGachaMaps(snap *Snapshot) {
+	for _, g := range Gachas.Rows(snap) {
+		snap.GachaByID[g.ID] = g
+		info := models.GachaInfo{
+			ID:              g.ID,
+			Name:            g.Name,
+			AssetbundleName: g.AssetbundleName,
+		}
+		for _, p := range g.GachaPickups {
+			snap.GachaPickups[g.ID] = append(snap.GachaPickups[g.ID], p.CardID)
+			snap.CardGachaMap[p.CardID] = append(snap.CardGachaMap[p.CardID], info)
+		}
+	}
+}
+
+func buildCardCostume3dMap(snap *Snapshot) {
+	for _, cc := range CardCostume3ds.Rows(snap) {
+		snap.CardCostume3dMap[cc.CardID] = append(snap.CardCostume3dMap[cc.CardID], cc.Costume3dID)
+	}
+}
+
+func buildCostume3dGroupMaps(snap *Snapshot) {
+	for _, c := range Costume3ds.Rows(snap) {
+		snap.Costume3dGroupIdMap[c.ID] = c.Costume3dGroupId
+		snap.Costume3dGroupMap[c.Costume3dGroupId] = append(snap.Costume3dGroupMap[c.Costume3dGroupId], c)
+	}
+}
+
+func buildCharacterMaps(snap *Snapshot) {
+	for _, c := range GameCharacters.Rows(snap) {
+		snap.CharacterByID[c.ID] = c
+		snap.CharactersByUnit[c.Unit] = append(snap.CharactersByUnit[c.Unit], c.ID)
+	}
+}
+
+// CardUnits returns the units a card counts towards: its character's unit,
+// plus the supported unit for virtual singer cards
+func (snap *Snapshot) CardUnits(card models.Card) []string {
+	var units []string
+	if ch, ok := snap.CharacterByID[card.CharacterID]; ok && ch.Unit != "" {
+		units = append(units, ch.Unit)
+	}
+	if card.SupportUnit != "" && card.SupportUnit != "none" {
+		units = append(units, card.SupportUnit)
+	}
+	return units
+}
+
+func buildCardSupplyMap(snap *Snapshot) {
+	for _, cs := range CardSupplies.Rows(snap) {
+		snap.CardSupplyTypeByID[cs.ID] = cs.CardSupplyType
+	}
+}
+
+// IsLimitedCard reports whether a card comes from a limited supply (limited, fes, collab, ...)
+func (snap *Snapshot) IsLimitedCard(card models.Card) bool {
+	supplyType := snap.CardSupplyTypeByID[card.CardSupplyID]
+	return supplyType != "" && supplyType != "normal"
+}
+
+// CardCostumes returns every costume part of the costume groups a card unlocks
+func (snap *Snapshot) CardCostumes(cardID int) []models.Costume3d {
+	seenGroupIds := make(map[int]bool)
+	result := []models.Costume3d{}
+	for _, cid := range snap.CardCostume3dMap[cardID] {
+		groupId, exists := snap.Costume3dGroupIdMap[cid]
+		if exists && !seenGroupIds[groupId] {
+			seenGroupIds[groupId] = true
+			result = append(result, snap.Costume3dGroupMap[groupId]...)
+		}
+	}
+	return result
+}
+
+func buildCardMaps(snap *Snapshot) {
+	for _, c := range Cards.Rows(snap) {
+		snap.CardByID[c.ID] = c
+		snap.CardsByCharacter[c.CharacterID] = append(snap.CardsByCharacter[c.CharacterID], c.ID)
+		for _, unit := range snap.CardUnits(c) {
+			snap.CardsByUnit[unit] = append(snap.CardsByUnit[unit], c.ID)
+		}
+	}
+}
+
+func buildSkillMap(snap *Snapshot) {
+	for _, sk := range Skills.Rows(snap) {
+		snap.SkillByID[sk.ID] = sk
+	}
+}
+
+func buildMusicMap(snap *Snapshot) {
+	for _, m := range Musics.Rows(snap) {
+		snap.MusicByID[m.ID] = m
+	}
+}
+
+func buildMusicDifficultyMap(snap *Snapshot) {
+	for _, d := range MusicDifficulties.Rows(snap) {
+		snap.MusicDifficultiesByMusic[d.MusicID] = append(snap.MusicDifficultiesByMusic[d.MusicID], d)
+	}
+	for _, diffs := range snap.MusicDifficultiesByMusic {
+		sort.SliceStable(diffs, func(i, j int) bool {
+			return musicDifficultyOrder[diffs[i].MusicDifficulty] < musicDifficultyOrder[diffs[j].MusicDifficulty]
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package masterdata
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"snowy_viewer/internal/models"
+)
+
+// Text index document types
+const (
+	DocEvent       = "event"
+	DocGacha       = "gacha"
+	DocCard        = "card"
+	DocMusic       = "music"
+	DocVirtualLive = "virtualLive"
+	DocCostume     = "costume"
+)
+
+// Match scores; primary fields are names and titles, secondary fields are
+// readings, creators and character names
+const (
+	scoreID              = 1000
+	scorePrimaryExact    = 100
+	scorePrimaryPrefix   = 80
+	scorePrimaryContains = 60
+	scoreSecondaryExact  = 50
+	scoreSecondaryPrefix = 40
+	scoreSecondaryMatch  = 30
+	scoreSpread          = 20
+)
+
+// textDoc is one searchable entity with its fields normalized by NormalizeText
+type textDoc struct {
+	docType         string
+	id              int
+	name            string
+	assetbundleName string
+	primary         []string
+	secondary       []string
+}
+
+// TextIndex is an inverted index from unigrams and bigrams of normalized
+// text to the documents containing them. Bigrams keep CJK lookups selective
+// without word segmentation; unigrams serve single character queries.
+type TextIndex struct {
+	docs     []textDoc
+	postings map[string][]int32
+	byID     map[string]map[int]int32
+}
+
+func newTextIndex() *TextIndex {
+	return &TextIndex{
+		postings: make(map[string][]int32),
+		byID:     make(map[string]map[int]int32),
+	}
+}
+
+// add indexes a document; empty fields are skipped
+func (idx *TextIndex) add(docType string, id int, name, assetbundleName string, primary, secondary []string) {
+	doc := textDoc{docType: docType, id: id, name: name, assetbundleName: assetbundleName}
+	pos := int32(len(idx.docs))
+	for _, f := range primary {
+		if f = NormalizeText(f); f != "" {
+			doc.primary = append(doc.primary, f)
+			idx.addGrams(f, pos)
+		}
+	}
+	for _, f := range secondary {
+		if f = NormalizeText(f); f != "" {
+			doc.secondary = append(doc.secondary, f)
+			idx.addGrams(f, pos)
+		}
+	}
+	idx.docs = append(idx.docs, doc)
+	if idx.byID[docType] == nil {
+		idx.byID[docType] = make(map[int]int32)
+	}
+	idx.byID[docType][id] = pos
+}
+
+func (idx *TextIndex) addGrams(text string, pos int32) {
+	forEachGram(text, func(gram string) {
+		list := idx.postings[gram]
+		if len(list) == 0 || list[len(list)-1] != pos {
+			idx.postings[gram] = append(list, pos)
+		}
+	})
+}
+
+// forEachGram calls fn with every unigram and bigram of text that does not
+// span a space. Grams are substrings of text, so no allocation happens.
+func forEachGram(text string, fn func(gram string)) {
+	prevStart := -1
+	for i, r := range text {
+		if r == ' ' {
+			prevStart = -1
+			continue
+		}
+		if prevStart >= 0 {
+			fn(text[prevStart:i])
+		}
+		fn(text[i : i+utf8.RuneLen(r)])
+		prevStart = i
+	}
+}
+
+// termGrams returns the grams to look up for a term: its bigrams, or the
+// term itself when it is a single character
+func termGrams(term string) []string {
+	var grams []string
+	prevStart := -1
+	for i := range term {
+		if prevStart >= 0 {
+			grams = append(grams, term[prevStart:i])
+		}
+		prevStart = i
+	}
+	if len(grams) == 0 {
+		grams = append(grams, term)
+	}
+	return grams
+}
+
+// candidates intersects the postings of every gram of every term. Documents
+// are returned in index order and still need verifying, since bigrams only
+// approximate substrings.
+func (idx *TextIndex) candidates(terms []string) []int32 {
+	var lists [][]int32
+	for _, term := range terms {
+		for _, gram := range termGrams(term) {
+			list, ok := idx.postings[gram]
+			if !ok {
+				return nil
+			}
+			lists = append(lists, list)
+		}
+	}
+	if len(lists) == 0 {
+		return nil
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	result := append([]int32(nil), lists[0]...)
+	for _, list := range lists[1:] {
+		kept := result[:0]
+		for _, pos := range result {
+			k := sort.Search(len(list), func(i int) bool { return list[i] >= pos })
+			if k < len(list) && list[k] == pos {
+				kept = append(kept, pos)
+			}
+		}
+		result = kept
+	}
+	return result
+}
+
+// score rates a document against a normalized query, returning 0 when a
+// term appears in none of its fields
+func (doc *textDoc) score(text string, terms []string) int {
+	best := 0
+	for _, field := range doc.primary {
+		best = max(best, fieldScore(field, text, scorePrimaryExact, scorePrimaryPrefix, scorePrimaryContains))
+	}
+	for _, field := range doc.secondary {
+		best = max(best, fieldScore(field, text, scoreSecondaryExact, scoreSecondaryPrefix, scoreSecondaryMatch))
+	}
+	if best > 0 {
+		return best
+	}
+	// Multi-term queries may match across fields, e.g. character name and card prefix
+	if len(terms) < 2 {
+		return 0
+	}
+	for _, term := range terms {
+		if !anyContains(doc.primary, term) && !anyContains(doc.secondary, term) {
+			return 0
+		}
+	}
+	return scoreSpread
+}
+
+func fieldScore(field, text string, exact, prefix, contains int) int {
+	switch {
+	case field == text:
+		return exact
+	case strings.HasPrefix(field, text):
+		return prefix
+	case strings.Contains(field, text):
+		return contains
+	}
+	return 0
+}
+
+func anyContains(fields []string, term string) bool {
+	for _, f := range fields {
+		if strings.Contains(f, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// Search scores the documents of the wanted types (all when types is nil)
+// against query. A numeric query also matches document IDs.
+func (idx *TextIndex) Search(query string, types map[string]bool) []models.SearchResult {
+	text := NormalizeText(query)
+	if text == "" {
+		return nil
+	}
+	terms := strings.Fields(text)
+	wants := func(docType string) bool {
+		return types == nil || types[docType]
+	}
+
+	var results []models.SearchResult
+	seen := make(map[int32]bool)
+	hit := func(pos int32, score int) {
+		doc := &idx.docs[pos]
+		seen[pos] = true
+		results = append(results, models.SearchResult{
+			Type:            doc.docType,
+			ID:              doc.id,
+			Name:            doc.name,
+			AssetbundleName: doc.assetbundleName,
+			Score:           score,
+		})
+	}
+
+	if id, err := strconv.Atoi(text); err == nil {
+		for docType, ids := range idx.byID {
+			if pos, ok := ids[id]; ok && wants(docType) {
+				hit(pos, scoreID)
+			}
+		}
+	}
+	for _, pos := range idx.candidates(terms) {
+		doc := &idx.docs[pos]
+		if seen[pos] || !wants(doc.docType) {
+			continue
+		}
+		if score := doc.score(text, terms); score > 0 {
+			hit(pos, score)
+		}
+	}
+	return results
+}
+
+// MatchIDs returns the IDs of docType documents matching query, by ID or
+// by text. It returns nil when query is empty after normalization.
+func (idx *TextIndex) MatchIDs(docType, query string) map[int]bool {
+	text := NormalizeText(query)
+	if text == "" {
+		return nil
+	}
+	terms := strings.Fields(text)
+	ids := make(map[int]bool)
+	if id, err := strconv.Atoi(text); err == nil {
+		if _, ok := idx.byID[docType][id]; ok {
+			ids[id] = true
+		}
+	}
+	for _, pos := range idx.candidates(terms) {
+		doc := &idx.docs[pos]
+		if doc.docType == docType && doc.score(text, terms) > 0 {
+			ids[doc.id] = true
+		}
+	}
+	return ids
+}
+
+// buildTextIndex indexes the searchable entities of a snapshot
+func buildTextIndex(snap *Snapshot) {
+	idx := newTextIndex()
+	for _, e := range Events.Rows(snap) {
+		idx.add(DocEvent, e.ID, e.Name, e.AssetbundleName, []string{e.Name}, nil)
+	}
+	for _, g := range Gachas.Rows(snap) {
+		idx.add(DocGacha, g.ID, g.Name, g.AssetbundleName, []string{g.Name}, nil)
+	}
+	for _, c := range Cards.Rows(snap) {
+		ch := snap.CharacterByID[c.CharacterID]
+		secondary := []string{
+			ch.FirstName + ch.GivenName,
+			ch.FirstNameRuby + ch.GivenNameRuby,
+			ch.GivenName,
+			c.CardSkillName,
+		}
+		idx.add(DocCard, c.ID, c.Prefix, c.AssetbundleName, []string{c.Prefix}, secondary)
+	}
+	for _, m := range Musics.Rows(snap) {
+		secondary := []string{m.Pronunciation, m.Composer, m.Lyricist, m.Arranger}
+		idx.add(DocMusic, m.ID, m.Title, m.AssetbundleName, []string{m.Title}, secondary)
+	}
+	for _, vl := range VirtualLives.Rows(snap) {
+		idx.add(DocVirtualLive, vl.ID, vl.Name, vl.AssetbundleName, []string{vl.Name}, nil)
+	}
+	// Costume parts and colors share a group; index each group once
+	seenGroups := make(map[int]bool)
+	for _, c := range Costume3ds.Rows(snap) {
+		if seenGroups[c.Costume3dGroupId] {
+			continue
+		}
+		seenGroups[c.Costume3dGroupId] = true
+		idx.add(DocCostume, c.ID, c.Name, c.AssetbundleName, []string{c.Name}, nil)
+	}
+	snap.Text = idx
+}
@@ -0,0 +1,125 @@
+package masterdata
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"snowy_viewer/internal/models"
+)
+
+// remoteVersion mirrors versions/current_version.json on the master servers
+type remoteVersion struct {
+	DataVersion  string `json:"dataVersion"`
+	AssetVersion string `json:"assetVersion"`
+}
+
+// versionFilePath returns the local version file of the data directory,
+// e.g. ./data/master -> ./data/master_version.txt
+func (s *Store) versionFilePath() string {
+	return filepath.Clean(s.localDataPath) + "_version.txt"
+}
+
+// versionURL derives the version endpoint from a master base URL,
+// e.g. https://host/master -> https://host/versions/current_version.json
+func versionURL(base string) string {
+	base = strings.TrimSuffix(base, "/")
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[:i]
+	}
+	return base + "/versions/current_version.json"
+}
+
+func (s *Store) readLocalVersion() (string, bool) {
+	content, err := os.ReadFile(s.versionFilePath())
+	if err != nil {
+		return "", false
+	}
+	version := strings.TrimSpace(string(content))
+	return version, version != ""
+}
+
+// compareVersions orders dotted numeric versions such as 3.9.0.10, missing
+// parts counting as 0. It reports false when either version is not one, e.g.
+// a git SHA, since such versions have no order.
+func compareVersions(a, b string) (int, bool) {
+	x, ok := parseDottedVersion(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := parseDottedVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < max(len(x), len(y)); i++ {
+		var xi, yi int
+		if i < len(x) {
+			xi = x[i]
+		}
+		if i < len(y) {
+			yi = y[i]
+		}
+		if c := cmp.Compare(xi, yi); c != 0 {
+			return c, true
+		}
+	}
+	return 0, true
+}
+
+func parseDottedVersion(v string) ([]int, bool) {
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || strings.TrimLeft(part, "0123456789") != "" {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+func (s *Store) fetchRemoteVersion(ctx context.Context) (string, string, error) {
+	var lastErr error = fmt.Errorf("no remote configured")
+	for _, base := range s.baseURLs {
+		url := versionURL(base)
+		var v remoteVersion
+		if _, _, lastErr = fetchJSON(ctx, url, &v); lastErr == nil {
+			if v.DataVersion == "" {
+				lastErr = fmt.Errorf("empty dataVersion in %s", url)
+				continue
+			}
+			return v.DataVersion, url, nil
+		}
+	}
+	return "", "", lastErr
+}
+
+// resolveVersion determines the master version of a freshly loaded snapshot.
+// If any file came from remote, the remote version is authoritative;
+// otherwise the local version file is used.
+func (s *Store) resolveVersion(ctx context.Context, sources map[string]models.DataSource) (string, string) {
+	usedRemote := false
+	for _, src := range sources {
+		if src.Type == models.DataSourceRemote {
+			usedRemote = true
+			break
+		}
+	}
+
+	if usedRemote {
+		if version, url, err := s.fetchRemoteVersion(ctx); err == nil {
+			return version, url
+		} else {
+			fmt.Printf("[%s] Warning: failed to fetch remote version: %v\n", s.region, err)
+		}
+	}
+	if version, ok := s.readLocalVersion(); ok {
+		return version, s.versionFilePath()
+	}
+	return "unknown", ""
+}
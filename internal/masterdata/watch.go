@@ -0,0 +1,160 @@
+package masterdata
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileState is the last observed state of a watched file
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// Watch polls the local data directory every interval and reloads the store
+// once files have stopped changing for the debounce period. A reload that
+// fails to parse keeps the previous snapshot, and no reload happens while the
+// loaded version is newer than the local one.
+func (s *Store) Watch(ctx context.Context, interval, debounce time.Duration) {
+	go func() {
+		states := s.scanLocalFiles(nil)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var pending []string
+		var lastChange time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next := s.scanLocalFiles(states)
+			if changed := diffFileStates(states, next); len(changed) > 0 {
+				pending = mergeNames(pending, changed)
+				lastChange = time.Now()
+			}
+			states = next
+
+			if len(pending) == 0 || time.Since(lastChange) < debounce {
+				continue
+			}
+			fmt.Printf("[%s] Local master data changed: %s\n", s.region, strings.Join(pending, ", "))
+			pending = nil
+
+			if loaded, local, newer := s.loadedNewerThanLocal(); newer {
+				fmt.Printf("[%s] Skipping hot reload: loaded version %s is newer than local %s\n", s.region, loaded, local)
+				continue
+			}
+
+			before := s.GetVersionInfo()
+			if err := s.load(ctx, loadOptions{strictLocal: true}); err != nil {
+				fmt.Printf("[%s] Hot reload failed, keeping previous data: %v\n", s.region, err)
+				continue
+			}
+			fmt.Printf("[%s] Hot reload complete: %s\n", s.region, summarizeChanges(before, s.GetVersionInfo()))
+		}
+	}()
+}
+
+// loadedNewerThanLocal reports whether the loaded snapshot, e.g. one the
+// refresher fetched from remote, is newer than the local version file, in
+// which case reloading local files would roll it back. Versions that are not
+// dotted numbers, such as git SHAs, are never considered newer.
+func (s *Store) loadedNewerThanLocal() (string, string, bool) {
+	local, ok := s.readLocalVersion()
+	if !ok {
+		return "", local, false
+	}
+	loaded := s.GetVersionInfo().Version
+	c, ok := compareVersions(loaded, local)
+	return loaded, local, ok && c > 0
+}
+
+// Watch starts the file watcher of every region
+func (s *Stores) Watch(ctx context.Context, interval, debounce time.Duration) {
+	for _, region := range s.regions {
+		s.stores[region].Watch(ctx, interval, debounce)
+	}
+}
+
+// scanLocalFiles stats the JSON files and the version file of the data directory.
+// Files are only rehashed when their size or mtime differs from prev.
+func (s *Store) scanLocalFiles(prev map[string]fileState) map[string]fileState {
+	paths, _ := filepath.Glob(filepath.Join(s.localDataPath, "*.json"))
+	paths = append(paths, s.versionFilePath())
+
+	states := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		old, seen := prev[path]
+		if seen && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			states[path] = old
+			continue
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			continue
+		}
+		states[path] = fileState{modTime: info.ModTime(), size: info.Size(), hash: hash}
+	}
+	return states
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// diffFileStates returns the base names of files added, removed or modified
+func diffFileStates(prev, next map[string]fileState) []string {
+	var changed []string
+	for path, st := range next {
+		if old, ok := prev[path]; !ok || old.hash != st.hash {
+			changed = append(changed, filepath.Base(path))
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, filepath.Base(path))
+		}
+	}
+	return changed
+}
+
+func mergeNames(names, more []string) []string {
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		seen[n] = true
+	}
+	for _, n := range more {
+		if !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
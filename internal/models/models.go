@@ -1,11 +1,39 @@
 package models
 
+import "encoding/json"
+
 // Master Data Structs
 type Event struct {
-	ID              int    `json:"id"`
-	Name            string `json:"name"`
-	AssetbundleName string `json:"assetbundleName"`
-	VirtualLiveId   int    `json:"virtualLiveId"`
+	ID                       int                       `json:"id"`
+	Name                     string                    `json:"name"`
+	AssetbundleName          string                    `json:"assetbundleName"`
+	VirtualLiveId            int                       `json:"virtualLiveId"`
+	EventType                string                    `json:"eventType"`
+	Unit                     string                    `json:"unit"`
+	StartAt                  int64                     `json:"startAt"`
+	AggregateAt              int64                     `json:"aggregateAt"`
+	RankingAnnounceAt        int64                     `json:"rankingAnnounceAt"`
+	DistributionStartAt      int64                     `json:"distributionStartAt"`
+	ClosedAt                 int64                     `json:"closedAt"`
+	DistributionEndAt        int64                     `json:"distributionEndAt"`
+	EventRankingRewardRanges []EventRankingRewardRange `json:"eventRankingRewardRanges"`
+}
+
+type EventRankingRewardRange struct {
+	ID                  int                  `json:"id"`
+	EventID             int                  `json:"eventId"`
+	FromRank            int                  `json:"fromRank"`
+	ToRank              int                  `json:"toRank"`
+	IsToRankBorder      bool                 `json:"isToRankBorder"`
+	EventRankingRewards []EventRankingReward `json:"eventRankingRewards"`
+}
+
+type EventRankingReward struct {
+	ID                        int    `json:"id"`
+	EventRankingRewardRangeID int    `json:"eventRankingRewardRangeId"`
+	Seq                       int    `json:"seq"`
+	ResourceBoxID             int    `json:"resourceBoxId"`
+	RewardConditionType       string `json:"rewardConditionType"`
 }
 
 type EventMusic struct {
@@ -15,15 +43,30 @@ type EventMusic struct {
 }
 
 type EventCard struct {
-	ID      int `json:"id"`
-	CardID  int `json:"cardId"`
-	EventID int `json:"eventId"`
+	ID              int     `json:"id"`
+	CardID          int     `json:"cardId"`
+	EventID         int     `json:"eventId"`
+	BonusRate       float64 `json:"bonusRate"`
+	LeaderBonusRate float64 `json:"leaderBonusRate"`
 }
 
 type VirtualLive struct {
-	ID              int    `json:"id"`
-	Name            string `json:"name"`
-	AssetbundleName string `json:"assetbundleName"`
+	ID                   int                   `json:"id"`
+	VirtualLiveType      string                `json:"virtualLiveType"`
+	Name                 string                `json:"name"`
+	AssetbundleName      string                `json:"assetbundleName"`
+	StartAt              int64                 `json:"startAt"`
+	EndAt                int64                 `json:"endAt"`
+	VirtualLiveSchedules []VirtualLiveSchedule `json:"virtualLiveSchedules"`
+}
+
+// VirtualLiveSchedule is one performance of a virtual live
+type VirtualLiveSchedule struct {
+	ID            int   `json:"id"`
+	VirtualLiveID int   `json:"virtualLiveId"`
+	Seq           int   `json:"seq"`
+	StartAt       int64 `json:"startAt"`
+	EndAt         int64 `json:"endAt"`
 }
 
 type EventInfo struct {
@@ -53,8 +96,30 @@ type Gacha struct {
 	AssetbundleName      string                `json:"assetbundleName"`
 	StartAt              int64                 `json:"startAt"`
 	EndAt                int64                 `json:"endAt"`
+	GachaCeilItemID      int                   `json:"gachaCeilItemId"`
 	GachaPickups         []GachaPickup         `json:"gachaPickups"`
 	GachaCardRarityRates []GachaCardRarityRate `json:"gachaCardRarityRates"`
+	// GachaDetails and GachaBehaviors are read from master data by
+	// UnmarshalJSON but left out of gacha responses
+	GachaDetails   []GachaDetail   `json:"-"`
+	GachaBehaviors []GachaBehavior `json:"-"`
+}
+
+// UnmarshalJSON reads a master gacha including its card pool and behaviors
+func (g *Gacha) UnmarshalJSON(data []byte) error {
+	type gacha Gacha
+	var master struct {
+		gacha
+		GachaDetails   []GachaDetail   `json:"gachaDetails"`
+		GachaBehaviors []GachaBehavior `json:"gachaBehaviors"`
+	}
+	if err := json.Unmarshal(data, &master); err != nil {
+		return err
+	}
+	*g = Gacha(master.gacha)
+	g.GachaDetails = master.GachaDetails
+	g.GachaBehaviors = master.GachaBehaviors
+	return nil
 }
 
 type GachaCardRarityRate struct {
@@ -69,6 +134,27 @@ type GachaPickup struct {
 	CardID  int `json:"cardId"`
 }
 
+// GachaDetail is a card in a gacha's pool; within a rarity, cards are drawn
+// in proportion to their weight
+type GachaDetail struct {
+	ID      int  `json:"id"`
+	GachaID int  `json:"gachaId"`
+	CardID  int  `json:"cardId"`
+	Weight  int  `json:"weight"`
+	IsWish  bool `json:"isWish"`
+}
+
+// GachaBehavior is a way to spin a gacha, e.g. a single pull or a 10-pull
+// guaranteeing one card of rarity 3 or above (over_rarity_3_once)
+type GachaBehavior struct {
+	ID                   int    `json:"id"`
+	GachaID              int    `json:"gachaId"`
+	GachaBehaviorType    string `json:"gachaBehaviorType"`
+	CostResourceType     string `json:"costResourceType"`
+	CostResourceQuantity int    `json:"costResourceQuantity"`
+	SpinCount            int    `json:"spinCount"`
+}
+
 // Costume Structs
 type CardCostume3d struct {
 	CardID      int `json:"cardId"`
@@ -88,6 +174,103 @@ type Costume3d struct {
 	ArchivePublishedAt int64  `json:"archivePublishedAt"`
 }
 
+// Card Structs
+type Card struct {
+	ID                       int    `json:"id"`
+	Seq                      int    `json:"seq"`
+	CharacterID              int    `json:"characterId"`
+	CardRarityType           string `json:"cardRarityType"`
+	Attr                     string `json:"attr"`
+	SupportUnit              string `json:"supportUnit"`
+	SkillID                  int    `json:"skillId"`
+	CardSkillName            string `json:"cardSkillName"`
+	SpecialTrainingSkillID   int    `json:"specialTrainingSkillId,omitempty"`
+	SpecialTrainingSkillName string `json:"specialTrainingSkillName,omitempty"`
+	Prefix                   string `json:"prefix"`
+	AssetbundleName          string `json:"assetbundleName"`
+	GachaPhrase              string `json:"gachaPhrase"`
+	ArchiveDisplayType       string `json:"archiveDisplayType"`
+	ArchivePublishedAt       int64  `json:"archivePublishedAt"`
+	ReleaseAt                int64  `json:"releaseAt"`
+	CardSupplyID             int    `json:"cardSupplyId"`
+}
+
+type CardSupply struct {
+	ID             int    `json:"id"`
+	CardSupplyType string `json:"cardSupplyType"`
+}
+
+type GameCharacter struct {
+	ID              int    `json:"id"`
+	Seq             int    `json:"seq"`
+	ResourceID      int    `json:"resourceId"`
+	FirstName       string `json:"firstName"`
+	GivenName       string `json:"givenName"`
+	FirstNameRuby   string `json:"firstNameRuby"`
+	GivenNameRuby   string `json:"givenNameRuby"`
+	Gender          string `json:"gender"`
+	Height          int    `json:"height"`
+	Figure          string `json:"figure"`
+	BreastSize      string `json:"breastSize"`
+	ModelName       string `json:"modelName"`
+	Unit            string `json:"unit"`
+	SupportUnitType string `json:"supportUnitType"`
+}
+
+type Skill struct {
+	ID                    int           `json:"id"`
+	ShortDescription      string        `json:"shortDescription"`
+	Description           string        `json:"description"`
+	DescriptionSpriteName string        `json:"descriptionSpriteName"`
+	SkillFilterID         int           `json:"skillFilterId"`
+	SkillEffects          []SkillEffect `json:"skillEffects"`
+}
+
+type SkillEffect struct {
+	ID                        int                 `json:"id"`
+	SkillEffectType           string              `json:"skillEffectType"`
+	ActivateNotesJudgmentType string              `json:"activateNotesJudgmentType"`
+	SkillEffectDetails        []SkillEffectDetail `json:"skillEffectDetails"`
+}
+
+type SkillEffectDetail struct {
+	ID                      int     `json:"id"`
+	Level                   int     `json:"level"`
+	ActivateEffectDuration  float64 `json:"activateEffectDuration"`
+	ActivateEffectValueType string  `json:"activateEffectValueType"`
+	ActivateEffectValue     float64 `json:"activateEffectValue"`
+}
+
+// Music Structs
+type Music struct {
+	ID                  int      `json:"id"`
+	Seq                 int      `json:"seq"`
+	ReleaseConditionID  int      `json:"releaseConditionId"`
+	Categories          []string `json:"categories"`
+	Title               string   `json:"title"`
+	Pronunciation       string   `json:"pronunciation"`
+	CreatorArtistID     int      `json:"creatorArtistId"`
+	Lyricist            string   `json:"lyricist"`
+	Composer            string   `json:"composer"`
+	Arranger            string   `json:"arranger"`
+	DancerCount         int      `json:"dancerCount"`
+	AssetbundleName     string   `json:"assetbundleName"`
+	PublishedAt         int64    `json:"publishedAt"`
+	ReleasedAt          int64    `json:"releasedAt"`
+	FillerSec           float64  `json:"fillerSec"`
+	IsNewlyWrittenMusic bool     `json:"isNewlyWrittenMusic"`
+	IsFullLength        bool     `json:"isFullLength"`
+}
+
+type MusicDifficulty struct {
+	ID                 int    `json:"id"`
+	MusicID            int    `json:"musicId"`
+	MusicDifficulty    string `json:"musicDifficulty"`
+	PlayLevel          int    `json:"playLevel"`
+	ReleaseConditionID int    `json:"releaseConditionId"`
+	TotalNoteCount     int    `json:"totalNoteCount"`
+}
+
 // Response Structs
 type GachaListItem struct {
 	ID              int    `json:"id"`
@@ -97,6 +280,10 @@ type GachaListItem struct {
 	StartAt         int64  `json:"startAt"`
 	EndAt           int64  `json:"endAt"`
 	PickupCardIds   []int  `json:"pickupCardIds"`
+
+	// Embedded with include=pickupCards,rarityRates
+	PickupCards []Card                `json:"pickupCards,omitempty"`
+	RarityRates []GachaCardRarityRate `json:"rarityRates,omitempty"`
 }
 
 type GachaListResponse struct {
@@ -109,4 +296,365 @@ type GachaListResponse struct {
 type GachaDetailResponse struct {
 	Gacha
 	PickupCardIds []int `json:"pickupCardIds"`
+
+	// Embedded with include=pickupCards,rarityRates
+	PickupCards []Card                `json:"pickupCards,omitempty"`
+	RarityRates []GachaCardRarityRate `json:"rarityRates,omitempty"`
+}
+
+// GachaRarityOdds is the chance, in percent, of drawing a rarity on one pull,
+// split between pickup and off-rate cards
+type GachaRarityOdds struct {
+	Rarity      string  `json:"rarity"`
+	Rate        float64 `json:"rate"`
+	PickupRate  float64 `json:"pickupRate"`
+	OffRate     float64 `json:"offRate"`
+	CardCount   int     `json:"cardCount"`
+	PickupCount int     `json:"pickupCount"`
+	TotalWeight int     `json:"totalWeight"`
+}
+
+// GachaCardOdds is the chance, in percent, of drawing one card on one pull:
+// its rarity's rate times its share of that rarity's weight
+type GachaCardOdds struct {
+	CardID int     `json:"cardId"`
+	Rarity string  `json:"rarity"`
+	Weight int     `json:"weight"`
+	Pickup bool    `json:"pickup"`
+	Rate   float64 `json:"rate"`
+}
+
+// GachaSlotOdds holds the odds of one pull slot
+type GachaSlotOdds struct {
+	Rarities []GachaRarityOdds `json:"rarities"`
+	Cards    []GachaCardOdds   `json:"cards"`
+}
+
+// GachaBehaviorOdds describes a spin behavior. When it guarantees a minimum
+// rarity, Guaranteed holds the odds of its last pull, where lower rarities
+// are folded into the guaranteed one.
+type GachaBehaviorOdds struct {
+	GachaBehavior
+	GuaranteedRarity string         `json:"guaranteedRarity,omitempty"`
+	Guaranteed       *GachaSlotOdds `json:"guaranteed,omitempty"`
+}
+
+type GachaRatesResponse struct {
+	GachaID   int                 `json:"gachaId"`
+	Name      string              `json:"name"`
+	GachaType string              `json:"gachaType"`
+	Rates     GachaSlotOdds       `json:"rates"`
+	Behaviors []GachaBehaviorOdds `json:"behaviors"`
+}
+
+// GachaPullResult is one simulated pull
+type GachaPullResult struct {
+	Pull       int    `json:"pull"`
+	CardID     int    `json:"cardId"`
+	Rarity     string `json:"rarity"`
+	Pickup     bool   `json:"pickup"`
+	Guaranteed bool   `json:"guaranteed,omitempty"`
+}
+
+// GachaExchange is a card taken with ceiling (spark) points
+type GachaExchange struct {
+	Pull   int `json:"pull"`
+	CardID int `json:"cardId"`
+}
+
+type GachaSimulationSummary struct {
+	RarityCounts         map[string]int `json:"rarityCounts"`
+	PickupCounts         map[int]int    `json:"pickupCounts"`
+	FirstPickupPull      int            `json:"firstPickupPull,omitempty"`
+	TargetObtained       bool           `json:"targetObtained"`
+	CeilPoints           int            `json:"ceilPoints"`
+	CostResourceType     string         `json:"costResourceType,omitempty"`
+	CostResourceQuantity int            `json:"costResourceQuantity"`
+}
+
+// GachaPullsBucket counts trials that got the target within a range of pulls
+type GachaPullsBucket struct {
+	From       int     `json:"from"`
+	To         int     `json:"to"`
+	Count      int     `json:"count"`
+	Cumulative float64 `json:"cumulative"`
+}
+
+// GachaPullDistribution is the Monte Carlo distribution of pulls needed to
+// get the target card, counting a spark exchange as obtained
+type GachaPullDistribution struct {
+	Trials      int                `json:"trials"`
+	MaxPulls    int                `json:"maxPulls"`
+	Mean        float64            `json:"mean"`
+	Median      int                `json:"median"`
+	P90         int                `json:"p90"`
+	P99         int                `json:"p99"`
+	Exchanged   int                `json:"exchanged"`
+	NotObtained int                `json:"notObtained"`
+	Buckets     []GachaPullsBucket `json:"buckets"`
+}
+
+type GachaSimulationResponse struct {
+	GachaID      int    `json:"gachaId"`
+	Mode         string `json:"mode"`
+	Seed         int64  `json:"seed"`
+	BehaviorID   int    `json:"behaviorId,omitempty"`
+	TargetCardID int    `json:"targetCardId,omitempty"`
+	SparkPulls   int    `json:"sparkPulls,omitempty"`
+
+	// Set in single mode
+	Pulls     int                     `json:"pulls,omitempty"`
+	Results   []GachaPullResult       `json:"results,omitempty"`
+	Exchanges []GachaExchange         `json:"exchanges,omitempty"`
+	Summary   *GachaSimulationSummary `json:"summary,omitempty"`
+
+	// Set in montecarlo mode
+	Distribution *GachaPullDistribution `json:"distribution,omitempty"`
+}
+
+type CardListItem struct {
+	Card
+	CardSupplyType string `json:"cardSupplyType"`
+
+	// Embedded with include=character,skill,event,gachas,costumes
+	Character *GameCharacter `json:"character,omitempty"`
+	Skill     *Skill         `json:"skill,omitempty"`
+	Event     *EventInfo     `json:"event,omitempty"`
+	Gachas    []GachaInfo    `json:"gachas,omitempty"`
+	Costumes  []Costume3d    `json:"costumes,omitempty"`
+}
+
+type CardListResponse struct {
+	Total int            `json:"total"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Cards []CardListItem `json:"cards"`
+}
+
+type CardDetailResponse struct {
+	Card
+	CardSupplyType string `json:"cardSupplyType"`
+
+	// Embedded with include=character,skill,event,gachas,costumes
+	Character *GameCharacter `json:"character,omitempty"`
+	Skill     *Skill         `json:"skill,omitempty"`
+	Event     *EventInfo     `json:"event,omitempty"`
+	Gachas    []GachaInfo    `json:"gachas,omitempty"`
+	Costumes  []Costume3d    `json:"costumes,omitempty"`
+}
+
+// CardGachaAppearance is a gacha featuring a card as a pickup. Appearances
+// overlapping an earlier one belong to the same run.
+type CardGachaAppearance struct {
+	GachaInfo
+	GachaType string `json:"gachaType"`
+	StartAt   int64  `json:"startAt"`
+	EndAt     int64  `json:"endAt"`
+	// Availability is one of the Availability* values
+	Availability string `json:"availability"`
+	// Kind is "debut" for the first run and "rerun" for later ones
+	Kind string `json:"kind"`
+	// Run counts runs from 0 for the debut
+	Run int `json:"run"`
+	// IntervalMs is the gap since the previous run ended, on the first
+	// appearance of each rerun
+	IntervalMs int64 `json:"intervalMs,omitempty"`
+}
+
+// Availabilities of a card in a gacha appearance
+const (
+	AvailabilityLimited   = "limited"
+	AvailabilityPermanent = "permanent"
+	AvailabilityUnknown   = "unknown"
+)
+
+type CardGachaHistoryResponse struct {
+	CardID         int                   `json:"cardId"`
+	CardRarityType string                `json:"cardRarityType"`
+	CardSupplyType string                `json:"cardSupplyType"`
+	Limited        bool                  `json:"limited"`
+	Appearances    []CardGachaAppearance `json:"appearances"`
+	DebutAt        int64                 `json:"debutAt,omitempty"`
+	LastAt         int64                 `json:"lastAt,omitempty"`
+	RerunCount     int                   `json:"rerunCount"`
+	// NeverRerun marks cards that debuted in a gacha and were not featured again
+	NeverRerun        bool  `json:"neverRerun"`
+	AverageIntervalMs int64 `json:"averageIntervalMs,omitempty"`
+	MinIntervalMs     int64 `json:"minIntervalMs,omitempty"`
+	MaxIntervalMs     int64 `json:"maxIntervalMs,omitempty"`
+}
+
+// Timeline entry types
+const (
+	TimelineEvent       = "event"
+	TimelineGacha       = "gacha"
+	TimelineVirtualLive = "virtualLive"
+)
+
+// TimelinePhase is a period within a timeline entry, e.g. an event's
+// aggregation or one virtual live performance
+type TimelinePhase struct {
+	Name string `json:"name"`
+	// Seq numbers virtual live performances
+	Seq     *int  `json:"seq,omitempty"`
+	StartAt int64 `json:"startAt"`
+	EndAt   int64 `json:"endAt"`
+}
+
+// TimelineEntry is an event, gacha or virtual live with its phases. Phases
+// may end after EndAt: an event's closed phase lasts until its rewards are
+// no longer distributed.
+type TimelineEntry struct {
+	Type            string          `json:"type"`
+	ID              int             `json:"id"`
+	Name            string          `json:"name"`
+	AssetbundleName string          `json:"assetbundleName"`
+	Subtype         string          `json:"subtype"`
+	StartAt         int64           `json:"startAt"`
+	EndAt           int64           `json:"endAt"`
+	Phases          []TimelinePhase `json:"phases"`
+}
+
+type TimelineResponse struct {
+	From    int64           `json:"from"`
+	To      int64           `json:"to"`
+	Total   int             `json:"total"`
+	Entries []TimelineEntry `json:"entries"`
+}
+
+// Version Structs
+const (
+	DataSourceLocal  = "local"
+	DataSourceRemote = "remote"
+)
+
+type DataSource struct {
+	Type     string `json:"type"`
+	Location string `json:"location"`
+	ETag     string `json:"etag,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+}
+
+type MasterVersionInfo struct {
+	Region        string                `json:"region"`
+	Version       string                `json:"version"`
+	VersionSource string                `json:"versionSource"`
+	LoadedAt      int64                 `json:"loadedAt"`
+	Sources       map[string]DataSource `json:"sources"`
+	Counts        map[string]int        `json:"counts"`
+}
+
+// Changelog Structs
+type EventCardChange struct {
+	CardID int       `json:"cardId"`
+	Event  EventInfo `json:"event"`
+}
+
+type ChangelogEntry struct {
+	FromVersion   string            `json:"fromVersion"`
+	Version       string            `json:"version"`
+	Time          int64             `json:"time"`
+	NewEvents     []EventInfo       `json:"newEvents"`
+	NewGachas     []GachaInfo       `json:"newGachas"`
+	NewEventCards []EventCardChange `json:"newEventCards"`
+	NewCostumes   []Costume3d       `json:"newCostumes"`
+}
+
+type ChangelogResponse struct {
+	Region  string           `json:"region"`
+	Version string           `json:"version"`
+	Entries []ChangelogEntry `json:"entries"`
+}
+
+// Event Response Structs
+type EventListItem struct {
+	ID              int    `json:"id"`
+	EventType       string `json:"eventType"`
+	Name            string `json:"name"`
+	AssetbundleName string `json:"assetbundleName"`
+	Unit            string `json:"unit"`
+	StartAt         int64  `json:"startAt"`
+	AggregateAt     int64  `json:"aggregateAt"`
+	ClosedAt        int64  `json:"closedAt"`
+	VirtualLiveId   int    `json:"virtualLiveId"`
+
+	// Embedded with include=cards,musics,virtualLive
+	Cards       []EventCardItem  `json:"cards,omitempty"`
+	Musics      []EventMusicItem `json:"musics,omitempty"`
+	VirtualLive *VirtualLiveInfo `json:"virtualLive,omitempty"`
+}
+
+type EventListResponse struct {
+	Total  int             `json:"total"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+	Events []EventListItem `json:"events"`
+}
+
+type EventCardItem struct {
+	CardID          int     `json:"cardId"`
+	BonusRate       float64 `json:"bonusRate"`
+	LeaderBonusRate float64 `json:"leaderBonusRate"`
+	Card            *Card   `json:"card,omitempty"`
+}
+
+type EventMusicItem struct {
+	MusicID int    `json:"musicId"`
+	Seq     int    `json:"seq"`
+	Music   *Music `json:"music,omitempty"`
+}
+
+type EventDetailResponse struct {
+	Event
+
+	// Embedded with include=cards,musics,virtualLive
+	Cards       []EventCardItem  `json:"cards,omitempty"`
+	Musics      []EventMusicItem `json:"musics,omitempty"`
+	VirtualLive *VirtualLiveInfo `json:"virtualLive,omitempty"`
+}
+
+// Music Response Structs
+type MusicDifficultyItem struct {
+	MusicDifficulty string `json:"musicDifficulty"`
+	PlayLevel       int    `json:"playLevel"`
+	TotalNoteCount  int    `json:"totalNoteCount"`
+}
+
+type MusicListItem struct {
+	Music
+	Difficulties []MusicDifficultyItem `json:"difficulties"`
+}
+
+type MusicListResponse struct {
+	Total  int             `json:"total"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+	Musics []MusicListItem `json:"musics"`
+}
+
+type MusicDetailResponse struct {
+	Music
+	Difficulties []MusicDifficulty `json:"difficulties"`
+	Events       []EventInfo       `json:"events"`
+}
+
+// Search Response Structs
+type SearchResult struct {
+	Type            string `json:"type"`
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	AssetbundleName string `json:"assetbundleName"`
+	Score           int    `json:"score"`
+}
+
+type SearchGroup struct {
+	Type    string         `json:"type"`
+	Total   int            `json:"total"`
+	Results []SearchResult `json:"results"`
+}
+
+type SearchResponse struct {
+	Query  string        `json:"query"`
+	Total  int           `json:"total"`
+	Groups []SearchGroup `json:"groups"`
 }
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"snowy_viewer/internal/bilibili"
 	"snowy_viewer/internal/cache"
@@ -17,6 +22,10 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Root context, cancelled on shutdown signals
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize cache (Redis with memory fallback)
 	appCache := cache.New(cfg.RedisURL)
 	defer appCache.Close()
@@ -24,15 +33,46 @@ func main() {
 	// Initialize Bilibili client
 	biliClient := bilibili.NewClient(appCache, cfg.BilibiliSessData, cfg.BilibiliCookie)
 
-	// Initialize and load master data
-	store := masterdata.NewStore(cfg.MasterDataPath)
-	if err := store.Fetch(); err != nil {
+	// Initialize and load master data for every configured region
+	var regionConfigs []masterdata.RegionConfig
+	for _, name := range cfg.Regions {
+		region, ok := masterdata.ParseRegion(name)
+		if !ok {
+			fmt.Printf("Warning: ignoring unknown region %q\n", name)
+			continue
+		}
+		regionConfigs = append(regionConfigs, masterdata.RegionConfig{
+			Region:        region,
+			LocalDataPath: cfg.MasterDataPathFor(string(region)),
+			BaseURLs:      cfg.MasterBaseURLsFor(string(region)),
+		})
+	}
+	defaultRegion, ok := masterdata.ParseRegion(cfg.DefaultRegion)
+	if !ok {
+		defaultRegion = masterdata.RegionJP
+	}
+	if len(regionConfigs) == 0 {
+		regionConfigs = append(regionConfigs, masterdata.RegionConfig{
+			Region:        defaultRegion,
+			LocalDataPath: cfg.MasterDataPath,
+		})
+	}
+	stores := masterdata.NewStores(defaultRegion, regionConfigs...)
+	if err := stores.FetchAll(); err != nil {
 		fmt.Printf("Initial fetch error: %v\n", err)
 	}
+	if cfg.UpdateInterval > 0 {
+		fmt.Printf("Checking remote master data every %s\n", cfg.UpdateInterval)
+		stores.StartPeriodicUpdate(ctx, cfg.UpdateInterval)
+	}
+	if cfg.WatchInterval > 0 {
+		fmt.Printf("Watching local master data every %s\n", cfg.WatchInterval)
+		stores.Watch(ctx, cfg.WatchInterval, cfg.WatchDebounce)
+	}
 
 	// Create router and register handlers
 	mux := http.NewServeMux()
-	handler := handlers.New(store, biliClient)
+	handler := handlers.New(stores, biliClient)
 	handler.RegisterRoutes(mux)
 
 	// Static file serving
@@ -53,8 +93,16 @@ func main() {
 	// Apply middlewares and start server
 	finalHandler := middleware.Chain(mux, middleware.CORS, middleware.Gzip)
 
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: finalHandler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
 	fmt.Printf("Server starting on :%s...\n", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, finalHandler); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		fmt.Printf("Error starting server: %s\n", err)
 	}
 }